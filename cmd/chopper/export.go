@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	var c cfgFlags
+	c.register(fs)
+	quiet := fs.Bool("q", false, "只输出错误和结果")
+	verbose := fs.Bool("v", false, "输出每个文件的处理过程, 代替进度行")
+	confirm := fs.Bool("confirm", false, "先列出导出计划, 确认后再执行")
+	timeout := fs.Duration("timeout", 0, "超时后取消导出, 例如 10m")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	cfgs, err := c.load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCfg
+	}
+
+	ctx, cancel := interruptContext(*timeout)
+	defer cancel()
+
+	code := exitOK
+	for _, cfg := range cfgs {
+		if ctx.Err() != nil {
+			return exitCanceled
+		}
+		fmt.Printf("==> %d %s\n", cfg.ID, cfg.DirPath)
+		exporter := c.exporter(cfg)
+		progress := newProgressPrinter(os.Stdout, *verbose)
+		if !*quiet {
+			exporter.OnEvent = progress.onEvent
+		}
+		plan, err := exporter.Plan(ctx)
+		progress.end()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			code = exitFailed
+			continue
+		}
+		if !*confirm {
+			printInvalid(os.Stderr, plan)
+		}
+		if plan.Unresolved() > 0 {
+			printConflicts(os.Stderr, plan.Conflicts)
+		}
+		if *confirm {
+			printPlan(os.Stdout, plan)
+			if !askYes("确认执行? [y/N] ") {
+				continue
+			}
+		}
+		res, err := exporter.Apply(ctx, plan)
+		progress.end()
+		if res != nil {
+			fmt.Print(res.Summary())
+			printConflicts(os.Stdout, res.Conflicts)
+		}
+		if ctx.Err() != nil {
+			fmt.Fprintln(os.Stderr, "已取消, 可以使用 undo 撤销已完成的修改")
+			return exitCanceled
+		} else if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			code = exitFailed
+		} else if len(res.Errors) > 0 && code == exitOK {
+			code = exitPartial
+		}
+	}
+	return code
+}
+
+// stdin 整个进程共用, 管道输入时每个 bufio.Reader 都会一次读走多行
+var stdin = bufio.NewReader(os.Stdin)
+
+func askYes(prompt string) bool {
+	fmt.Print(prompt)
+	line, _ := stdin.ReadString('\n')
+	line = strings.ToLower(strings.TrimSpace(line))
+	return line == "y" || line == "yes"
+}
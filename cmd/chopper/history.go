@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dragon8897/chopper/core"
+)
+
+func printHistory(w io.Writer, rec *core.HistoryRecord, verbose bool) {
+	fmt.Fprintln(w, rec.Summary())
+	if !verbose {
+		return
+	}
+	if rec.Commit != "" {
+		fmt.Fprintf(w, "  提交   %s\n", rec.Commit)
+	}
+	for _, r := range rec.Renamed {
+		fmt.Fprintf(w, "  改名   %s -> %s\n", r.From, r.To)
+	}
+	for _, file := range rec.Scaled {
+		fmt.Fprintf(w, "  九宫格 %s\n", file)
+	}
+	for _, file := range rec.Uploaded {
+		fmt.Fprintf(w, "  上传   %s\n", file)
+	}
+	for _, err := range rec.Errors {
+		fmt.Fprintf(w, "  错误   %s\n", err.Error())
+	}
+	if rec.Report != "" {
+		fmt.Fprintf(w, "  报告   %s\n", rec.Report)
+	}
+}
+
+func runHistory(args []string) int {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	var c cfgFlags
+	c.register(fs)
+	file := fs.String("file", "", "只列出处理过名字中包含该字符串的文件的导出, 原文件名和新文件名都会匹配")
+	limit := fs.Int("n", 20, "每个配置最多列出的记录数, 0 表示全部")
+	verbose := fs.Bool("v", false, "列出每次导出处理的文件")
+	asJSON := fs.Bool("json", false, "以 json 格式输出")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	cfgs, err := c.load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCfg
+	}
+
+	code := exitOK
+	histories := map[int64][]core.HistoryRecord{}
+	for _, cfg := range cfgs {
+		records, err := core.LoadHistory(cfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			code = exitFailed
+			continue
+		}
+		var found []core.HistoryRecord
+		for i := range records {
+			if *file != "" && !records[i].Touches(*file) {
+				continue
+			}
+			found = append(found, records[i])
+			if *limit > 0 && len(found) >= *limit {
+				break
+			}
+		}
+		if *asJSON {
+			histories[cfg.ID] = found
+			continue
+		}
+		fmt.Printf("==> %d %s\n", cfg.ID, cfg.DirPath)
+		for i := range found {
+			printHistory(os.Stdout, &found[i], *verbose || *file != "")
+		}
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(histories); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			return exitFailed
+		}
+	}
+	return code
+}
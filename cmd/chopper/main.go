@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dragon8897/chopper/core"
+)
+
+// 退出码
+const (
+	exitOK     = 0
+	exitFailed = 1
+	exitUsage  = 2
+	exitCfg    = 3
+	// 导出完成, 但有文件处理失败
+	exitPartial = 4
+	// 导出被 Ctrl-C 或 -timeout 取消
+	exitCanceled = 5
+)
+
+type command struct {
+	usage string
+	run   func(args []string) int
+}
+
+var commands = map[string]command{
+	"export": {
+		usage: "执行导出: 改名, 九宫格, 压缩, git 上传, 机器人通知",
+		run:   runExport,
+	},
+	"plan": {
+		usage: "只计算并列出将要进行的改名, 九宫格裁剪和 git 变动, 不修改文件",
+		run:   runPlan,
+	},
+	"watch": {
+		usage: "监视资源目录, 文件变动停止后自动导出",
+		run:   runWatch,
+	},
+	"history": {
+		usage: "列出导出历史, 可以按文件名查找某个文件是什么时候被谁修改的",
+		run:   runHistory,
+	},
+	"search": {
+		usage: "按原文件名 (中文) 或导出后的文件名查找文件名对照表",
+		run:   runSearch,
+	},
+	"undo": {
+		usage: "撤销最近一次导出对资源目录的修改",
+		run:   runUndo,
+	},
+	"list-configs": {
+		usage: "列出所有导出配置",
+		run:   runListConfigs,
+	},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: chopper <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "使用 chopper <command> -h 查看各命令的参数")
+}
+
+// cfgFlags 各命令共用的配置选择参数
+type cfgFlags struct {
+	file      string
+	id        int64
+	collision string
+	stages    string
+	workers   int
+	full      bool
+}
+
+func (c *cfgFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.file, "config", "", "json 配置文件, 默认读取界面程序保存的配置")
+	fs.Int64Var(&c.id, "id", 0, "只使用指定 id 的配置")
+	fs.StringVar(&c.collision, "collision", "", "改名冲突的处理方式: "+strings.Join(core.CollisionPolicies, ", ")+", 默认使用配置中的设置")
+	fs.StringVar(&c.stages, "stages", "", "逗号分隔的导出阶段: "+strings.Join(core.StageNames(), ", ")+", 默认使用配置中的设置")
+	fs.IntVar(&c.workers, "workers", 0, "同时处理图片的协程数, 默认使用配置中的设置或 CPU 核数")
+	fs.BoolVar(&c.full, "full", false, "忽略上次导出的记录, 处理所有文件")
+}
+
+func (c *cfgFlags) exporter(cfg core.ChopperCfg) *core.Exporter {
+	exporter := core.NewExporter(cfg)
+	exporter.Full = c.full
+	return exporter
+}
+
+func (c *cfgFlags) load() ([]core.ChopperCfg, error) {
+	var cfgs []core.ChopperCfg
+	var err error
+	if c.file != "" {
+		cfgs, err = core.LoadCfgFile(c.file)
+	} else {
+		cfgs, err = core.LoadPreferenceCfgs()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.id != 0 {
+		cfg, err := core.FindCfg(cfgs, c.id)
+		if err != nil {
+			return nil, err
+		}
+		cfgs = []core.ChopperCfg{*cfg}
+	}
+	for i := range cfgs {
+		if c.collision != "" {
+			cfgs[i].Collision = c.collision
+		}
+		if c.stages != "" {
+			cfgs[i].Stages = strings.Split(c.stages, ",")
+		}
+		if c.workers > 0 {
+			cfgs[i].Workers = c.workers
+		}
+	}
+	return cfgs, nil
+}
+
+// interruptContext 收到 Ctrl-C 或 SIGTERM 时取消, timeout 大于 0 时超时也会取消
+func interruptContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-sig:
+			fmt.Fprintln(os.Stderr, "正在取消...")
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(sig)
+	}()
+	return ctx, cancel
+}
+
+func runListConfigs(args []string) int {
+	fs := flag.NewFlagSet("list-configs", flag.ContinueOnError)
+	var c cfgFlags
+	c.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	cfgs, err := c.load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCfg
+	}
+	for _, cfg := range cfgs {
+		stages := cfg.Stages
+		if stages == nil {
+			stages = core.DefaultStages
+		}
+		fmt.Printf("%d\t%s\t%s\t%s\n", cfg.ID, cfg.DirPath, cfg.Git.URL, strings.Join(stages, ","))
+	}
+	return exitOK
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(exitUsage)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(exitUsage)
+	}
+	os.Exit(cmd.run(os.Args[2:]))
+}
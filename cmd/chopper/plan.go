@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dragon8897/chopper/core"
+)
+
+func printPlan(w io.Writer, plan *core.Plan) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "原文件\t新文件\t九宫格\tgit\t词典")
+	for _, item := range plan.Items {
+		if item.Unchanged || item.Error != "" {
+			continue
+		}
+		to := "-"
+		if item.Renamed() {
+			to = item.To
+		}
+		scale := "-"
+		if item.Scale != nil {
+			scale = fmt.Sprint(item.Scale)
+		}
+		change := "-"
+		if item.Git != "" {
+			change = item.Git
+		}
+		dict := "-"
+		if len(item.Overrides) > 0 {
+			dict = strings.Join(item.Overrides, ",")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", item.From, to, scale, change, dict)
+	}
+	tw.Flush()
+	if unchanged := len(plan.Items) - plan.Changed(); unchanged > 0 {
+		fmt.Fprintf(w, "另有 %d 个文件和上次导出相比没有变化\n", unchanged)
+	}
+	printInvalid(w, plan)
+	printUntranslated(w, plan)
+	printConflicts(w, plan.Conflicts)
+}
+
+// printUntranslated 列出词汇表中没有而使用了拼音的词, 按文件数量从多到少
+func printUntranslated(w io.Writer, plan *core.Plan) {
+	terms := plan.Untranslated()
+	if len(terms) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "未翻译的词 %d:\n", len(terms))
+	for _, term := range terms {
+		fmt.Fprintf(w, "  %s (%d): %s\n", term.Term, len(term.Files), strings.Join(term.Files, ", "))
+	}
+}
+
+// printInvalid 列出文件名无法处理的文件和有警告的文件, 无法处理的文件不会被导出
+func printInvalid(w io.Writer, plan *core.Plan) {
+	if plan.Invalid() > 0 {
+		fmt.Fprintf(w, "无法处理的文件 %d:\n", plan.Invalid())
+		for _, item := range plan.Items {
+			if item.Error != "" {
+				fmt.Fprintf(w, "  %s: %s\n", item.From, item.Error)
+			}
+		}
+	}
+	if plan.Warned() > 0 {
+		fmt.Fprintf(w, "文件名警告 %d:\n", plan.Warned())
+		for _, item := range plan.Items {
+			for _, warning := range item.Warnings {
+				fmt.Fprintf(w, "  %s: %s\n", item.From, warning)
+			}
+		}
+	}
+}
+
+func printConflicts(w io.Writer, conflicts []core.Conflict) {
+	if len(conflicts) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "改名冲突 %d:\n", len(conflicts))
+	for _, c := range conflicts {
+		resolution := c.Resolution
+		if resolution == "" {
+			resolution = "未处理"
+		}
+		fmt.Fprintf(w, "  %s (%s)\n", c.To, resolution)
+		for _, file := range c.Files {
+			fmt.Fprintf(w, "    %s\n", file)
+		}
+	}
+}
+
+func runPlan(args []string) int {
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	var c cfgFlags
+	c.register(fs)
+	asJSON := fs.Bool("json", false, "以 json 格式输出")
+	untranslated := fs.Bool("untranslated", false, "以词汇表的格式输出未翻译的词, 填上英文后可以加入词汇表")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	cfgs, err := c.load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCfg
+	}
+
+	ctx, cancel := interruptContext(0)
+	defer cancel()
+
+	code := exitOK
+	plans := map[int64]*core.Plan{}
+	terms := map[string]string{}
+	for _, cfg := range cfgs {
+		plan, err := c.exporter(cfg).Plan(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			code = exitFailed
+			continue
+		}
+		if *untranslated {
+			for _, term := range plan.Untranslated() {
+				terms[term.Term] = ""
+			}
+			continue
+		}
+		if *asJSON {
+			plans[cfg.ID] = plan
+			continue
+		}
+		fmt.Printf("==> %d %s\n", cfg.ID, cfg.DirPath)
+		printPlan(os.Stdout, plan)
+	}
+	if *untranslated || *asJSON {
+		var v interface{} = plans
+		if *untranslated {
+			v = terms
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			return exitFailed
+		}
+	}
+	return code
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dragon8897/chopper/core"
+)
+
+// progressPrinter 输出导出进度, 每个阶段一行
+// 终端中同一阶段的进度覆盖在同一行上, 输出到文件或管道时只在阶段结束后输出最后的进度
+type progressPrinter struct {
+	w       io.Writer
+	verbose bool
+	tty     bool
+	stage   string
+	line    string
+}
+
+func newProgressPrinter(f *os.File, verbose bool) *progressPrinter {
+	p := &progressPrinter{w: f, verbose: verbose}
+	if info, err := f.Stat(); err == nil {
+		p.tty = info.Mode()&os.ModeCharDevice != 0
+	}
+	return p
+}
+
+func (p *progressPrinter) onEvent(e core.Event) {
+	progress := e.File == "" && e.Message == ""
+	if p.verbose {
+		if progress {
+			return
+		}
+		if e.File == "" {
+			fmt.Fprintf(p.w, "[%s] %s\n", e.Stage, e.Message)
+		} else {
+			fmt.Fprintf(p.w, "[%s] %s %s\n", e.Stage, e.File, e.Message)
+		}
+		return
+	}
+	if e.File != "" {
+		return
+	}
+	if p.stage != e.Stage {
+		p.end()
+	}
+	p.stage = e.Stage
+	if !progress {
+		p.line = fmt.Sprintf("[%s] %s", e.Stage, e.Message)
+	} else if e.Total > 0 {
+		p.line = fmt.Sprintf("[%s] %d/%d", e.Stage, e.Done, e.Total)
+	} else {
+		p.line = fmt.Sprintf("[%s] %d", e.Stage, e.Done)
+	}
+	if p.tty {
+		fmt.Fprint(p.w, "\r\033[K"+p.line)
+	}
+}
+
+// end 结束当前的进度行
+func (p *progressPrinter) end() {
+	if p.stage == "" {
+		return
+	}
+	if p.tty {
+		fmt.Fprintln(p.w)
+	} else {
+		fmt.Fprintln(p.w, p.line)
+	}
+	p.stage = ""
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/dragon8897/chopper/core"
+)
+
+func printMapping(w io.Writer, entries []core.MappingEntry) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "原文件\t导出文件\t首次导出\t最近导出\thash")
+	for _, e := range entries {
+		hash := e.Hash
+		if len(hash) > 8 {
+			hash = hash[:8]
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.Origin, e.Path,
+			e.FirstExport.Local().Format("2006-01-02 15:04:05"), e.LastExport.Local().Format("2006-01-02 15:04:05"), hash)
+	}
+	tw.Flush()
+}
+
+func runSearch(args []string) int {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	var c cfgFlags
+	c.register(fs)
+	name := fs.String("name", "", "原文件名或导出后的文件名中包含的字符串, 为空时列出全部")
+	asJSON := fs.Bool("json", false, "以 json 格式输出")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	cfgs, err := c.load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCfg
+	}
+
+	code := exitOK
+	results := map[int64][]core.MappingEntry{}
+	for _, cfg := range cfgs {
+		entries, err := core.LoadMapping(cfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			code = exitFailed
+			continue
+		}
+		found := core.SearchMapping(entries, *name)
+		if *asJSON {
+			results[cfg.ID] = found
+			continue
+		}
+		if len(found) == 0 {
+			continue
+		}
+		fmt.Printf("==> %d %s\n", cfg.ID, cfg.DirPath)
+		printMapping(os.Stdout, found)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			return exitFailed
+		}
+	}
+	return code
+}
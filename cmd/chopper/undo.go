@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dragon8897/chopper/core"
+)
+
+func runUndo(args []string) int {
+	fs := flag.NewFlagSet("undo", flag.ContinueOnError)
+	var c cfgFlags
+	c.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	cfgs, err := c.load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCfg
+	}
+
+	code := exitOK
+	for _, cfg := range cfgs {
+		fmt.Printf("==> %d %s\n", cfg.ID, cfg.DirPath)
+		j, err := core.UndoLast(cfg)
+		if err == core.ErrorNoJournal {
+			fmt.Println(err)
+			continue
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			code = exitFailed
+			continue
+		}
+		fmt.Printf("已撤销 %s 的导出, 恢复 %d 项修改\n", j.Time.Format("2006-01-02 15:04:05"), len(j.Entries))
+	}
+	return code
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/dragon8897/chopper/core"
+)
+
+func runWatch(args []string) int {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	var c cfgFlags
+	c.register(fs)
+	verbose := fs.Bool("v", false, "输出每个文件的处理过程")
+	delay := fs.Duration("delay", core.DefaultWatchDelay, "最后一次变动后等待多久再导出")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	cfgs, err := c.load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCfg
+	}
+	// 没有指定 id 时只监视开启了自动导出的配置
+	if c.id == 0 {
+		var watched []core.ChopperCfg
+		for _, cfg := range cfgs {
+			if cfg.Watch {
+				watched = append(watched, cfg)
+			}
+		}
+		cfgs = watched
+	}
+	if len(cfgs) == 0 {
+		fmt.Fprintln(os.Stderr, "没有开启自动导出的配置, 可以使用 -id 指定要监视的配置")
+		return exitCfg
+	}
+
+	ctx, cancel := interruptContext(0)
+	defer cancel()
+
+	// 多个配置同时监视, 输出需要加锁避免交错
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	code := exitOK
+	for _, cfg := range cfgs {
+		watcher := core.NewWatcher(cfg)
+		watcher.Delay = *delay
+		id := cfg.ID
+		watcher.OnEvent = func(e core.Event) {
+			if !*verbose && e.Stage != core.StageWatch || e.Message == "" {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if e.File == "" {
+				fmt.Printf("%d [%s] %s\n", id, e.Stage, e.Message)
+			} else {
+				fmt.Printf("%d [%s] %s %s\n", id, e.Stage, e.File, e.Message)
+			}
+		}
+		watcher.OnRun = func(run core.WatchRun) {
+			mu.Lock()
+			defer mu.Unlock()
+			fmt.Printf("==> %d %s 自动导出, %d 处变动\n", id, run.Time.Format("15:04:05"), run.Changes)
+			if run.Result != nil {
+				fmt.Print(run.Result.Summary())
+				printConflicts(os.Stdout, run.Result.Conflicts)
+			}
+			if run.Err != nil {
+				fmt.Fprintln(os.Stderr, "error:", run.Err)
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := watcher.Run(ctx)
+			if err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+				fmt.Fprintf(os.Stderr, "error: %d %v\n", id, err)
+				code = exitFailed
+			}
+		}()
+	}
+	wg.Wait()
+	return code
+}
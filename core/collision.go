@@ -0,0 +1,176 @@
+package core
+
+import (
+	"errors"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 改名冲突的处理方式
+const (
+	// CollisionFail 存在冲突时拒绝导出
+	CollisionFail = "fail"
+	// CollisionSuffix 冲突的文件依次加上 _2, _3 ... 后缀
+	CollisionSuffix = "suffix"
+	// CollisionKeep 冲突的文件保留原文件名
+	CollisionKeep = "keep"
+)
+
+var CollisionPolicies = []string{CollisionFail, CollisionSuffix, CollisionKeep}
+
+var (
+	ErrorNameConflict = errors.New("存在改名冲突, 请先处理冲突的文件")
+)
+
+// Conflict 多个文件改名后得到同一个文件名
+type Conflict struct {
+	To    string   `json:"to"`
+	Files []string `json:"files"`
+	// 处理方式, 为空表示没有处理
+	Resolution string `json:"resolution,omitempty"`
+}
+
+// nameKey 文件名比较时忽略大小写, macOS 和 Windows 的文件系统默认不区分大小写
+func nameKey(file string) string {
+	return strings.ToLower(file)
+}
+
+// planTarget 导出后文件所在的位置, 文件名无法处理的文件不会改名, 仍然占用原来的位置
+func planTarget(item PlanItem) string {
+	if item.Error != "" {
+		return item.From
+	}
+	return item.To
+}
+
+// moving 文件是否会被改名
+func moving(item PlanItem) bool {
+	return item.Error == "" && item.Renamed()
+}
+
+// findConflicts 找出导出后位置相同, 并且其中有文件会被改名的文件, 返回 目标 -> 计划项下标
+func findConflicts(items []PlanItem) map[string][]int {
+	targets := map[string][]int{}
+	for i, item := range items {
+		key := nameKey(planTarget(item))
+		targets[key] = append(targets[key], i)
+	}
+	conflicts := map[string][]int{}
+	for key, indexes := range targets {
+		if len(indexes) < 2 {
+			continue
+		}
+		for _, i := range indexes {
+			if moving(items[i]) {
+				conflicts[key] = indexes
+				break
+			}
+		}
+	}
+	return conflicts
+}
+
+func suffixName(file string, n int) string {
+	ext := path.Ext(file)
+	return strings.TrimSuffix(file, ext) + "_" + strconv.Itoa(n) + ext
+}
+
+// resolveConflicts 按 policy 处理计划中的改名冲突, 返回按 CollisionSuffix 得到新文件名的计划项下标
+func resolveConflicts(plan *Plan, policy string) []int {
+	conflicts := findConflicts(plan.Items)
+	if len(conflicts) == 0 {
+		return nil
+	}
+	var keys []string
+	for key := range conflicts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	used := map[string]bool{}
+	for _, item := range plan.Items {
+		used[nameKey(item.To)] = true
+		used[nameKey(item.From)] = true
+	}
+	var suffixed []int
+	for _, key := range keys {
+		indexes := conflicts[key]
+		// 不会改名的文件优先保留目标文件名, 其余按原文件名排序
+		sort.SliceStable(indexes, func(a, b int) bool {
+			itemA, itemB := plan.Items[indexes[a]], plan.Items[indexes[b]]
+			if moving(itemA) != moving(itemB) {
+				return !moving(itemA)
+			}
+			return itemA.From < itemB.From
+		})
+		conflict := Conflict{
+			To:         planTarget(plan.Items[indexes[0]]),
+			Resolution: policy,
+		}
+		for _, index := range indexes {
+			conflict.Files = append(conflict.Files, plan.Items[index].From)
+		}
+		for n, index := range indexes[1:] {
+			item := &plan.Items[index]
+			// 不会改名的文件留在原来的位置, 不需要处理
+			if !moving(*item) {
+				continue
+			}
+			switch policy {
+			case CollisionSuffix:
+				for i := n + 2; ; i++ {
+					to := suffixName(conflict.To, i)
+					if !used[nameKey(to)] {
+						item.To = to
+						used[nameKey(to)] = true
+						suffixed = append(suffixed, index)
+						break
+					}
+				}
+			case CollisionKeep:
+				item.To = item.From
+			default:
+				conflict.Resolution = ""
+			}
+		}
+		plan.Conflicts = append(plan.Conflicts, conflict)
+	}
+
+	// 处理后仍然冲突的文件 (例如保留的原文件名与其他文件的新文件名相同) 视为未处理
+	remains := findConflicts(plan.Items)
+	keys = keys[:0]
+	for key := range remains {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		found := false
+		for i := range plan.Conflicts {
+			if nameKey(plan.Conflicts[i].To) == key {
+				plan.Conflicts[i].Resolution = ""
+				found = true
+			}
+		}
+		if !found {
+			conflict := Conflict{To: planTarget(plan.Items[remains[key][0]])}
+			for _, index := range remains[key] {
+				conflict.Files = append(conflict.Files, plan.Items[index].From)
+			}
+			plan.Conflicts = append(plan.Conflicts, conflict)
+		}
+	}
+	return suffixed
+}
+
+// Unresolved 没有处理的冲突数量
+func (plan *Plan) Unresolved() int {
+	count := 0
+	for _, c := range plan.Conflicts {
+		if c.Resolution == "" {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestResolveConflicts(t *testing.T) {
+	items := func() []PlanItem {
+		return []PlanItem{
+			{From: "确定.png", To: "ok.png"},
+			{From: "OK.png", To: "ok.png"},
+			{From: "好.png", To: "ok.png"},
+			{From: "取消.png", To: "cancel.png"},
+		}
+	}
+	tests := []struct {
+		policy     string
+		to         []string
+		unresolved int
+	}{
+		{CollisionFail, []string{"ok.png", "ok.png", "ok.png", "cancel.png"}, 1},
+		// 按原文件名排序, 第一个文件保留目标文件名
+		{CollisionSuffix, []string{"ok_3.png", "ok.png", "ok_2.png", "cancel.png"}, 0},
+		{CollisionKeep, []string{"确定.png", "ok.png", "好.png", "cancel.png"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			plan := &Plan{Items: items()}
+			resolveConflicts(plan, tt.policy)
+			var to []string
+			for _, item := range plan.Items {
+				to = append(to, item.To)
+			}
+			if !reflect.DeepEqual(to, tt.to) {
+				t.Errorf("to = %v, want %v", to, tt.to)
+			}
+			if len(plan.Conflicts) != 1 || plan.Unresolved() != tt.unresolved {
+				t.Errorf("conflicts = %+v", plan.Conflicts)
+			}
+		})
+	}
+
+	// 保留的原文件名和其他文件的新文件名相同时视为未处理
+	plan := &Plan{Items: []PlanItem{
+		{From: "a.png", To: "b.png"},
+		{From: "c.png", To: "b.png"},
+		{From: "d.png", To: "c.png"},
+	}}
+	resolveConflicts(plan, CollisionKeep)
+	if plan.Unresolved() == 0 {
+		t.Errorf("conflicts = %+v", plan.Conflicts)
+	}
+}
+
+// 文件名不符合规则而不会改名的文件仍然占用原来的位置, 其他文件不能改名到这里
+func TestConflictWithInvalidFile(t *testing.T) {
+	cfg := ChopperCfg{
+		CaseStyle:  CaseKebab,
+		Template:   "{type}_{name}",
+		Validation: ValidationCfg{Pattern: "^[a-z_]+$"},
+		Stages:     []string{StageRename},
+	}
+	for _, policy := range CollisionPolicies {
+		t.Run(policy, func(t *testing.T) {
+			dir := tempDir(t)
+			writePNG(t, dir, "btn_ok.png", 4, 4)
+			writePNG(t, dir, "@按钮-ok.png", 8, 8)
+			cfg := cfg
+			cfg.DirPath = dir
+			cfg.Collision = policy
+			e := NewExporter(cfg)
+			plan, err := e.Plan(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(plan.Conflicts) != 1 {
+				t.Fatalf("conflicts = %+v", plan.Conflicts)
+			}
+			if policy == CollisionFail {
+				if plan.Unresolved() != 1 {
+					t.Errorf("conflict should be unresolved: %+v", plan.Conflicts)
+				}
+				return
+			}
+			if _, err := e.Apply(context.Background(), plan); err != nil {
+				t.Fatal(err)
+			}
+			if w := pngWidth(t, dir, "btn_ok.png"); w != 4 {
+				t.Errorf("btn_ok.png overwritten by a %dpx image", w)
+			}
+		})
+	}
+}
+
+func TestMoveRefusesExistingFile(t *testing.T) {
+	dir := tempDir(t)
+	writePNG(t, dir, "a.png", 4, 4)
+	writePNG(t, dir, "b.png", 8, 8)
+	cfg := ChopperCfg{DirPath: dir}
+	journal, err := newJournal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer journal.close()
+	job := &Job{Cfg: cfg, journal: journal}
+	if err := job.Move("b.png", "a.png"); !errors.Is(err, ErrorTargetExists) {
+		t.Fatalf("Move onto an existing file: %v", err)
+	}
+	if pngWidth(t, dir, "a.png") != 4 || pngWidth(t, dir, "b.png") != 8 || len(journal.Entries) != 0 {
+		t.Error("files changed by a refused move")
+	}
+	if err := job.Move("b.png", "c/b.png"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Sketch 重新导出已经导出过的切图时替换之前导出的文件, 撤销后恢复
+func TestReexportReplaces(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  ChopperCfg
+		to   string
+	}{
+		{"default", ChopperCfg{}, "主界面/btn_que4ding4.png"},
+		{"template index", ChopperCfg{Template: "{type}_{index}"}, "主界面/btn_1.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := tempDir(t)
+			writePNG(t, dir, "主界面/@按钮-确定.png", 4, 4)
+			cfg := tt.cfg
+			cfg.DirPath = dir
+			cfg.Stages = []string{StageRename}
+			e := NewExporter(cfg)
+			if _, err := e.Run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			writePNG(t, dir, "主界面/@按钮-确定.png", 8, 8)
+			plan, err := e.Plan(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(plan.Conflicts) != 0 || len(plan.Items) != 1 || plan.Items[0].To != tt.to {
+				t.Fatalf("plan = %+v, conflicts = %+v", plan.Items, plan.Conflicts)
+			}
+			res, err := e.Apply(context.Background(), plan)
+			if err != nil || len(res.Errors) != 0 {
+				t.Fatal(err, res.Errors)
+			}
+			if pngWidth(t, dir, tt.to) != 8 {
+				t.Error("exported file not replaced")
+			}
+			if again, _ := e.Plan(context.Background()); again.Changed() != 0 {
+				t.Errorf("%d files changed after replace", again.Changed())
+			}
+			if _, err := UndoLast(cfg); err != nil {
+				t.Fatal(err)
+			}
+			if pngWidth(t, dir, tt.to) != 4 || pngWidth(t, dir, "主界面/@按钮-确定.png") != 8 {
+				t.Error("undo did not restore the replaced file")
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package core
+
+type ChopperCfg struct {
+	ID      int64  `json:"id"`
+	DirPath string `json:"dir"`
+	Git     struct {
+		URL      string `json:"url"`
+		UserName string `json:"name"`
+		Password string `json:"pwd"`
+	} `json:"git"`
+	Robot struct {
+		Name    string `json:"name"`
+		Content string `json:"content"`
+	} `json:"robot"`
+	// 改名冲突的处理方式, 见 CollisionPolicies, 默认 CollisionFail
+	Collision string `json:"collision,omitempty"`
+	// 按顺序执行的导出阶段, 为 nil 时使用 DefaultStages, 为空时不执行任何阶段
+	Stages []string `json:"stages"`
+	// 同时处理图片的协程数, 0 表示使用 CPU 核数
+	Workers int `json:"workers,omitempty"`
+	// 文件名前缀表, 为 nil 时使用 DefaultPrefixes
+	Prefixes []PrefixTag `json:"prefixes"`
+	// 拼音风格, 见 PinyinStyles, 默认 tone3
+	PinyinStyle string `json:"pinyin_style,omitempty"`
+	// 同一段汉字的拼音音节之间的分隔符, 默认不分隔
+	Separator string `json:"separator,omitempty"`
+	// 词之间的连接方式和大小写, 见 CaseStyles, 默认 CaseKeep
+	CaseStyle string `json:"case,omitempty"`
+	// 汉字的转换方式, 见 Backends, 默认 BackendPinyin
+	Backend string `json:"backend,omitempty"`
+	// 词汇表文件, 默认为资源目录中的 .chopper/glossary.json
+	Glossary string `json:"glossary,omitempty"`
+	// 文件夹名也按同样的规则转换
+	Dirs bool `json:"dirs,omitempty"`
+	// 词典, 优先于拼音库, 见 LoadOverrides
+	Overrides []Override `json:"overrides,omitempty"`
+	// 命名模板, 例如 {dir}_{type}_{name}, 为空时使用默认的命名方式, 见 templateVars
+	Template string `json:"template,omitempty"`
+	// 倍图后缀的形式和需要的倍数
+	Retina RetinaCfg `json:"retina"`
+	// 导出后文件名的检查规则
+	Validation ValidationCfg `json:"validation"`
+	// 监视文件夹变动并自动导出
+	Watch bool `json:"watch,omitempty"`
+}
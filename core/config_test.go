@@ -0,0 +1,23 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// 保存配置后再读取, 没有配置的阶段和清空的阶段不能混淆
+func TestCfgStagesRoundTrip(t *testing.T) {
+	for _, stages := range [][]string{nil, {}} {
+		data, err := json.Marshal(ChopperCfg{Stages: stages})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cfg ChopperCfg
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			t.Fatal(err)
+		}
+		if (cfg.Stages == nil) != (stages == nil) || len(cfg.Stages) != len(stages) {
+			t.Errorf("stages %#v loaded as %#v", stages, cfg.Stages)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package core
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mozillazg/go-pinyin"
+)
+
+// Override 词典中的一条: 名字中出现 Phrase 时使用指定的读音或英文单词代替默认的拼音
+type Override struct {
+	Phrase string `json:"phrase"`
+	// 空格分隔, 带声调数字的拼音, 例如 chong2 zhi4, 输出时转换为配置的拼音风格
+	Pinyin string `json:"pinyin,omitempty"`
+	// 英文单词, 多个单词用空格分隔, 例如 long press
+	English string `json:"english,omitempty"`
+
+	// 来自词汇表, 不计入用到的词典中的词
+	glossary bool
+}
+
+// DictPath 资源目录中的词典文件, 可以和资源一起由团队共同维护
+func DictPath(cfg ChopperCfg) string {
+	return path.Join(cfg.DirPath, ".chopper", "dict.json")
+}
+
+// LoadOverrides 读取资源目录中的词典和配置中的词典, 同一个词以配置中的为准
+func LoadOverrides(cfg ChopperCfg) ([]Override, error) {
+	var overrides []Override
+	data, err := ioutil.ReadFile(DictPath(cfg))
+	if err == nil {
+		err = json.Unmarshal(data, &overrides)
+		if err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return append(overrides, cfg.Overrides...), nil
+}
+
+// dict 按词长从长到短匹配的词典
+type dict struct {
+	overrides []Override
+	phrases   [][]rune
+}
+
+func newDict(overrides []Override) *dict {
+	index := map[string]int{}
+	d := &dict{}
+	for _, o := range overrides {
+		if o.Phrase == "" || o.Pinyin == "" && o.English == "" {
+			continue
+		}
+		// 和文件名一样统一写法后再匹配
+		o.Phrase = normalizeName(o.Phrase)
+		// 后出现的覆盖先出现的
+		if i, ok := index[o.Phrase]; ok {
+			d.overrides[i] = o
+			continue
+		}
+		index[o.Phrase] = len(d.overrides)
+		d.overrides = append(d.overrides, o)
+	}
+	sort.SliceStable(d.overrides, func(a, b int) bool {
+		return len([]rune(d.overrides[a].Phrase)) > len([]rune(d.overrides[b].Phrase))
+	})
+	for _, o := range d.overrides {
+		d.phrases = append(d.phrases, []rune(o.Phrase))
+	}
+	return d
+}
+
+// match 返回从 runes 开头匹配到的最长的词
+func (d *dict) match(runes []rune) (*Override, int) {
+	for i, phrase := range d.phrases {
+		if len(phrase) > len(runes) {
+			continue
+		}
+		matched := true
+		for j, r := range phrase {
+			if runes[j] != r {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return &d.overrides[i], len(phrase)
+		}
+	}
+	return nil, 0
+}
+
+// overrideSyllable 把词典中带声调数字的拼音转换为配置的拼音风格
+// r 的多音字读音中有相同读音时使用拼音库的转换结果, 否则原样使用
+func overrideSyllable(r rune, syllable string, pyArgs pinyin.Args) string {
+	syllable = strings.ToLower(syllable)
+	if unicode.Is(unicode.Han, r) {
+		tone3 := pyArgs
+		tone3.Style = pinyin.Tone3
+		tone3.Heteronym = true
+		styled := pyArgs
+		styled.Heteronym = true
+		readings := pinyin.SinglePinyin(r, tone3)
+		outputs := pinyin.SinglePinyin(r, styled)
+		for i, reading := range readings {
+			if i < len(outputs) && (reading == syllable || strings.TrimRight(reading, "012345") == syllable) {
+				return outputs[i]
+			}
+		}
+	}
+	return syllable
+}
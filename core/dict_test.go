@@ -0,0 +1,31 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanFileOverrides(t *testing.T) {
+	english := []Override{{Phrase: "确定", English: "ok"}, {Phrase: "长按", English: "long press"}}
+	reading := []Override{{Phrase: "重庆", Pinyin: "chong2 qing4"}}
+	testPlanFile(t, []planFileCase{
+		{"english", ChopperCfg{CaseStyle: CaseSnake, Overrides: english}, "@按钮-确定.png", "btn_ok.png", nil, 0, false},
+		{"english words", ChopperCfg{CaseStyle: CaseCamel, Overrides: english}, "@按钮-长按确定.png", "btnLongPressOk.png", nil, 0, false},
+		{"polyphonic", ChopperCfg{CaseStyle: CaseSnake}, "@背景-重庆.png", "bg_zhong4qing4.png", nil, 0, false},
+		{"reading", ChopperCfg{CaseStyle: CaseSnake, Overrides: reading}, "@背景-重庆.png", "bg_chong2qing4.png", nil, 0, false},
+		// 读音按配置的拼音风格输出
+		{"reading style", ChopperCfg{PinyinStyle: "normal", Overrides: reading}, "重庆.png", "chongqing.png", nil, 0, false},
+	})
+}
+
+// 计划中记录用到的词典中的词
+func TestOverridesRecorded(t *testing.T) {
+	n, err := newNamer(ChopperCfg{Overrides: []Override{{Phrase: "长按", English: "long press"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := n.planFile("@按钮-长按确定.png")
+	if !reflect.DeepEqual(item.Overrides, []string{"长按"}) {
+		t.Errorf("overrides = %v", item.Overrides)
+	}
+}
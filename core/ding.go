@@ -1,6 +1,7 @@
-package main
+package core
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
@@ -29,9 +30,16 @@ func hmacSha256(key string, msg string) []byte {
 	return h.Sum(nil)
 }
 
-func robot(cfg ChopperCfg) error {
+// 钉钉返回的结果, errcode 不为 0 时消息没有发出
+type robotResp struct {
+	Errcode int    `json:"errcode"`
+	Errmsg  string `json:"errmsg"`
+}
+
+// robot 发送通知, 返回消息是否真的发出, 没有配置或者不认识的机器人不发送
+func robot(ctx context.Context, cfg ChopperCfg) (bool, error) {
 	if cfg.Robot.Name == "" || cfg.Robot.Content == "" {
-		return nil
+		return false, nil
 	}
 
 	var secret string
@@ -42,7 +50,7 @@ func robot(cfg ChopperCfg) error {
 	}
 
 	if secret == "" || token == "" {
-		return nil
+		return false, nil
 	}
 
 	timeStamp := time.Now().Unix() * 1000
@@ -57,8 +65,28 @@ func robot(cfg ChopperCfg) error {
 	robotMsg.Text.Content = cfg.Robot.Content
 	content, err := json.Marshal(robotMsg)
 	if err != nil {
-		return err
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(string(content)))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("机器人通知失败: %s", resp.Status)
+	}
+	var result robotResp
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return false, err
+	}
+	if result.Errcode != 0 {
+		return false, fmt.Errorf("机器人通知失败: %d %s", result.Errcode, result.Errmsg)
 	}
-	_, err = http.Post(url, "application/json", strings.NewReader(string(content)))
-	return err
+	return true, nil
 }
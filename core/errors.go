@@ -0,0 +1,52 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// FileError 处理单个文件时发生的错误, 不会中断整个导出
+type FileError struct {
+	File  string
+	Stage string
+	Err   error
+}
+
+func (e *FileError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("[%s] %v", e.Stage, e.Err)
+	}
+	return fmt.Sprintf("[%s] %s: %v", e.Stage, e.File, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+type fileErrorJSON struct {
+	File  string `json:"file,omitempty"`
+	Stage string `json:"stage"`
+	Cause string `json:"cause"`
+}
+
+func (e *FileError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fileErrorJSON{
+		File:  e.File,
+		Stage: e.Stage,
+		Cause: e.Err.Error(),
+	})
+}
+
+// UnmarshalJSON 读取导出记录中的错误, 原始的错误类型无法恢复, 只保留错误信息
+func (e *FileError) UnmarshalJSON(data []byte) error {
+	var v fileErrorJSON
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+	e.File = v.File
+	e.Stage = v.Stage
+	e.Err = errors.New(v.Cause)
+	return nil
+}
@@ -0,0 +1,282 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+)
+
+var (
+	ErrorNoDirPath = errors.New("目标文件夹没有配置")
+	ErrorNotDir    = errors.New("目标位置不是一个文件夹")
+	ErrorBusy      = errors.New("目标文件夹正在导出, 请稍后再试")
+)
+
+// 正在导出或撤销的文件夹, 避免手动导出, 自动导出和撤销同时修改同一个文件夹
+var exporting sync.Map
+
+// 导出流程的各个阶段
+const (
+	StageWalk     = "walk"
+	StageRename   = "rename"
+	Stage9Scale   = "9scale"
+	StageCompress = "compress"
+	StageUpload   = "upload"
+	StageRobot    = "robot"
+	// 计算文件名时发现的问题, 不是可以配置的阶段
+	StagePlan = "plan"
+	// 导出完成后记录文件状态, 不是可以配置的阶段
+	StageManifest = "manifest"
+	// 导出成功后清理较早的修改日志, 不是可以配置的阶段
+	StageJournal = "journal"
+)
+
+// Event 导出过程中发出的进度事件
+type Event struct {
+	Stage   string
+	File    string
+	Message string
+	// 阶段进度, Total 为 0 表示总数未知
+	Done  int
+	Total int
+}
+
+// Rename 一次文件改名
+type Rename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Result 一次导出的结果, 路径均相对于 ChopperCfg.DirPath
+type Result struct {
+	Files []string
+	// 本次修改日志的 ID, 用于撤销
+	Journal   string
+	Conflicts []Conflict
+	Renamed   []Rename
+	Scaled    []string
+	Uploaded  git.Status
+	// 上传到 git 的提交 hash, 没有上传时为空
+	Commit   string
+	Notified bool
+	// 本次导出用到的词典中的词 -> 文件数量
+	Overrides map[string]int
+	// 使用词汇表时, 词汇表中没有而使用了拼音的词
+	Untranslated []string
+	// html 报告的路径, json 报告在同一文件夹中
+	Report string
+	// 和上次导出相比没有变化的文件数量
+	Unchanged int
+	// 因为前面的阶段出错而没有继续处理的文件
+	Skipped []string
+	Errors  []*FileError
+	// 新文件名不符合检查规则但仍然导出的文件
+	Warnings []*FileError
+}
+
+// Exporter 不依赖界面的导出流程: 遍历, 改名, 九宫格, 压缩, git 上传, 机器人通知
+type Exporter struct {
+	Cfg ChopperCfg
+	// 同一时间只会有一个 OnEvent 调用, 但可能来自不同的协程
+	OnEvent func(Event)
+	// 忽略上次导出的记录, 处理所有文件
+	Full bool
+
+	mu sync.Mutex
+}
+
+func NewExporter(cfg ChopperCfg) *Exporter {
+	return &Exporter{
+		Cfg: cfg,
+	}
+}
+
+func (e *Exporter) emit(stage string, file string, message string) {
+	e.send(Event{
+		Stage:   stage,
+		File:    file,
+		Message: message,
+	})
+}
+
+func (e *Exporter) progress(stage string, done int, total int) {
+	e.send(Event{
+		Stage: stage,
+		Done:  done,
+		Total: total,
+	})
+}
+
+func (e *Exporter) send(event Event) {
+	if e.OnEvent == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.OnEvent(event)
+}
+
+// Run 计划并立即执行导出
+func (e *Exporter) Run(ctx context.Context) (*Result, error) {
+	plan, err := e.Plan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return e.Apply(ctx, plan)
+}
+
+// Apply 按计划依次执行配置中启用的各个阶段
+// ctx 取消后在文件之间停止, 已完成的修改都记录在日志中, 可以撤销
+func (e *Exporter) Apply(ctx context.Context, plan *Plan) (*Result, error) {
+	cfg := e.Cfg
+	if plan.Unresolved() > 0 {
+		return nil, ErrorNameConflict
+	}
+	if _, busy := exporting.LoadOrStore(cfg.DirPath, true); busy {
+		return nil, ErrorBusy
+	}
+	defer exporting.Delete(cfg.DirPath)
+	stages, err := CfgStages(cfg)
+	if err != nil {
+		return nil, err
+	}
+	journal, err := newJournal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer journal.close()
+
+	job := &Job{
+		Cfg:  cfg,
+		Plan: plan,
+		Result: &Result{
+			Journal:   journal.ID,
+			Conflicts: plan.Conflicts,
+		},
+		stages:   stages,
+		exporter: e,
+		journal:  journal,
+		manifest: plan.manifest,
+	}
+	job.sizes = make([]int64, len(plan.Items))
+	for i, item := range plan.Items {
+		job.Files = append(job.Files, item.From)
+		if info, err := os.Stat(path.Join(cfg.DirPath, item.From)); err == nil {
+			job.sizes[i] = info.Size()
+		}
+	}
+	// 文件名无法处理的文件直接记为错误, 之后的阶段不再处理
+	for i, item := range plan.Items {
+		if item.Error != "" {
+			job.Fail(i, StagePlan, errors.New(item.Error))
+		}
+		if item.Unchanged {
+			continue
+		}
+		for _, warning := range item.Warnings {
+			job.Result.Warnings = append(job.Result.Warnings, &FileError{
+				File:  item.From,
+				Stage: StagePlan,
+				Err:   errors.New(warning),
+			})
+		}
+	}
+	for _, stage := range stages {
+		err = ctx.Err()
+		if err == nil {
+			err = stage.Run(ctx, job)
+		}
+		if err != nil {
+			break
+		}
+	}
+	// 中途失败或取消时只记录已经改名的文件, 没有完成的文件下次导出时会重新处理
+	if err == nil {
+		err = job.updateManifest(ctx, true)
+		if perr := pruneJournals(cfg, journalKeep); perr != nil {
+			job.Fail(-1, StageJournal, perr)
+		}
+	} else if merr := job.updateManifest(ctx, false); merr != nil {
+		job.Fail(-1, StageManifest, merr)
+	}
+	job.finish()
+	// 中断的导出也生成报告和记录历史
+	report, rerr := job.writeReport(ctx, err)
+	if rerr != nil {
+		job.Fail(-1, StageReport, rerr)
+	}
+	job.Result.Report = report
+	herr := appendHistory(cfg, newHistoryRecord(cfg, job.Result, err))
+	if err == nil {
+		err = herr
+	}
+	return job.Result, err
+}
+
+// Summary 导出结果的文字摘要: 成功, 跳过和失败的文件
+func (res *Result) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "改名 %d, 九宫格 %d, 上传 %d, 未变化 %d, 跳过 %d, 错误 %d\n",
+		len(res.Renamed), len(res.Scaled), len(res.Uploaded), res.Unchanged, len(res.Skipped), len(res.Errors))
+	if len(res.Uploaded) > 0 {
+		b.WriteString("已上传:\n")
+		var files []string
+		for file := range res.Uploaded {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			b.WriteString("  " + file + "\n")
+		}
+	}
+	if len(res.Overrides) > 0 {
+		var phrases []string
+		for phrase := range res.Overrides {
+			phrases = append(phrases, phrase)
+		}
+		sort.Strings(phrases)
+		b.WriteString("词典:")
+		for _, phrase := range phrases {
+			fmt.Fprintf(&b, " %s %d", phrase, res.Overrides[phrase])
+		}
+		b.WriteString("\n")
+	}
+	if len(res.Untranslated) > 0 {
+		b.WriteString("未翻译: " + strings.Join(res.Untranslated, ", ") + "\n")
+	}
+	if res.Commit != "" {
+		b.WriteString("提交: " + res.Commit + "\n")
+	}
+	if res.Notified {
+		b.WriteString("已发送机器人通知\n")
+	}
+	if res.Report != "" {
+		b.WriteString("报告: " + res.Report + "\n")
+	}
+	if len(res.Skipped) > 0 {
+		b.WriteString("已跳过:\n")
+		for _, file := range res.Skipped {
+			b.WriteString("  " + file + "\n")
+		}
+	}
+	if len(res.Warnings) > 0 {
+		b.WriteString("警告:\n")
+		for _, warning := range res.Warnings {
+			b.WriteString("  " + warning.Error() + "\n")
+		}
+	}
+	if len(res.Errors) > 0 {
+		b.WriteString("错误:\n")
+		for _, err := range res.Errors {
+			b.WriteString("  " + err.Error() + "\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// walkDir 遍历 dir 下所有文件, 跳过 . 和 __ 开头的文件和文件夹, 每找到一个文件调用一次 scanned
+func walkDir(dir string, base string, scanned func(file string)) (files []string, err error) {
+	dirs, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range dirs {
+		name := file.Name()
+		if strings.HasPrefix(name, "__") {
+			continue
+		}
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		filePath := path.Join(dir, name)
+		basePath := path.Join(base, name)
+		if file.IsDir() {
+			subFiles, err := walkDir(filePath, basePath, scanned)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, subFiles...)
+		} else {
+			files = append(files, basePath)
+			if scanned != nil {
+				scanned(basePath)
+			}
+		}
+	}
+	return files, nil
+}
+
+func copyFile(src, dst string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return
+	}
+	defer in.Close()
+
+	_ = os.MkdirAll(path.Dir(dst), os.ModePerm)
+	out, err := os.Create(dst)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if e := out.Close(); e != nil {
+			err = e
+		}
+	}()
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		return
+	}
+
+	err = out.Sync()
+	if err != nil {
+		return
+	}
+
+	si, err := os.Stat(src)
+	if err != nil {
+		return
+	}
+	err = os.Chmod(dst, si.Mode())
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func execute(ctx context.Context, name string, arg ...string) error {
+	cmd := exec.CommandContext(ctx, name, arg...)
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+	err = cmd.Wait()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// removeEmptyDirs 从 dir 开始逐级向上删除空文件夹, 直到 root 或者遇到不为空的文件夹
+func removeEmptyDirs(root string, dir string) {
+	for dir != "." && dir != "/" && dir != "" {
+		if os.Remove(path.Join(root, dir)) != nil {
+			return
+		}
+		dir = path.Dir(dir)
+	}
+}
@@ -0,0 +1,170 @@
+package core
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// uploadProgress gitUpload 的进度回调
+type uploadProgress struct {
+	// 每拷贝一个文件到 .remote 后调用, err 为拷贝失败的原因
+	copied func(file string, err error)
+	// git clone, pull, push 的远端进度输出
+	remote io.Writer
+}
+
+func gitEnabled(cfg ChopperCfg) bool {
+	return cfg.Git.Password != "" && cfg.Git.UserName != "" && cfg.Git.URL != ""
+}
+
+// gitUpload 拷贝文件到 .remote 仓库, 删除改名前的旧文件, 更新文件名对照表并推送, 返回变动的文件和提交的 hash
+// origins 为上传的文件 -> 原文件, 见 MappingPath
+func gitUpload(ctx context.Context, cfg ChopperCfg, files []string, origins map[string]string, removed []string, progress uploadProgress) (git.Status, string, error) {
+	if len(files) == 0 && len(removed) == 0 {
+		return nil, "", nil
+	}
+	if !gitEnabled(cfg) {
+		return nil, "", nil
+	}
+	dir := path.Join(cfg.DirPath, ".remote")
+	_, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		_, err := git.PlainCloneContext(ctx, dir, false, &git.CloneOptions{
+			Auth: &http.BasicAuth{
+				Username: cfg.Git.UserName,
+				Password: cfg.Git.Password,
+			},
+			URL:      cfg.Git.URL,
+			Progress: progress.remote,
+		})
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	d, err := os.Stat(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	if !d.IsDir() {
+		return nil, "", err
+	}
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		return nil, "", err
+	}
+	ref, err := r.Head()
+	if err != nil {
+		return nil, "", err
+	}
+	err = w.Reset(&git.ResetOptions{
+		Commit: ref.Hash(),
+		Mode:   git.HardReset,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	err = w.PullContext(ctx, &git.PullOptions{
+		RemoteName: "origin",
+		Auth: &http.BasicAuth{
+			Username: cfg.Git.UserName,
+			Password: cfg.Git.Password,
+		},
+		Progress: progress.remote,
+	})
+	// 本地已经是最新的不算错误
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return nil, "", err
+	}
+
+	// 之前以旧文件名上传过的文件, 旧文件名被其他文件使用时不删除
+	uploading := map[string]bool{}
+	for _, f := range files {
+		uploading[f] = true
+	}
+	for _, f := range removed {
+		if uploading[f] {
+			continue
+		}
+		if _, err := os.Stat(path.Join(dir, f)); err != nil {
+			continue
+		}
+		_, err = w.Remove(f)
+		if err == index.ErrEntryNotFound {
+			// 没有提交过的文件直接删除
+			err = os.Remove(path.Join(dir, f))
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		removeEmptyDirs(dir, path.Dir(f))
+	}
+
+	copied := map[string]string{}
+	for _, f := range files {
+		if ctx.Err() != nil {
+			return nil, "", ctx.Err()
+		}
+		err = copyFile(path.Join(cfg.DirPath, f), path.Join(dir, f))
+		if progress.copied != nil {
+			progress.copied(f, err)
+		}
+		if err == nil {
+			copied[f] = origins[f]
+		}
+	}
+	err = updateMapping(dir, copied, removed, time.Now())
+	if err != nil {
+		return nil, "", err
+	}
+
+	s, err := w.Status()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(s) == 0 {
+		return nil, "", nil
+	}
+
+	_, err = w.Add(".")
+	if err != nil {
+		return nil, "", err
+	}
+
+	hash, err := w.Commit("update res", &git.CommitOptions{
+		Author: &object.Signature{
+			Name:  "chopper",
+			Email: "chopper@didiapp.com",
+			When:  time.Now(),
+		},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = r.PushContext(ctx, &git.PushOptions{
+		Auth: &http.BasicAuth{
+			Username: cfg.Git.UserName,
+			Password: cfg.Git.Password,
+		},
+		Progress: progress.remote,
+	})
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	return s, hash.String(), nil
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+)
+
+// 文件名中汉字的转换方式
+const (
+	// BackendPinyin 使用拼音库
+	BackendPinyin = "pinyin"
+	// BackendGlossary 使用团队维护的中英词汇表翻译为英文, 词汇表中没有的词使用拼音
+	BackendGlossary = "glossary"
+)
+
+var Backends = []string{BackendPinyin, BackendGlossary}
+
+var ErrorUnknownBackend = errors.New("未知的转换方式")
+
+// GlossaryPath 词汇表文件, 没有配置时使用资源目录中的 .chopper/glossary.json
+func GlossaryPath(cfg ChopperCfg) string {
+	if cfg.Glossary != "" {
+		return cfg.Glossary
+	}
+	return path.Join(cfg.DirPath, ".chopper", "glossary.json")
+}
+
+// LoadGlossary 读取词汇表, 格式为 {"主界面": "main menu"}, 文件不存在时为空
+func LoadGlossary(cfg ChopperCfg) (map[string]string, error) {
+	glossary := map[string]string{}
+	data, err := ioutil.ReadFile(GlossaryPath(cfg))
+	if os.IsNotExist(err) {
+		return glossary, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &glossary)
+	if err != nil {
+		return nil, err
+	}
+	return glossary, nil
+}
+
+// glossaryOverrides 把词汇表转换为词典中的词, 词典中的词优先
+func glossaryOverrides(glossary map[string]string) []Override {
+	var overrides []Override
+	for phrase, english := range glossary {
+		overrides = append(overrides, Override{Phrase: phrase, English: english, glossary: true})
+	}
+	sort.Slice(overrides, func(a, b int) bool {
+		return overrides[a].Phrase < overrides[b].Phrase
+	})
+	return overrides
+}
+
+// UntranslatedTerm 词汇表中没有, 使用了拼音的词
+type UntranslatedTerm struct {
+	Term  string   `json:"term"`
+	Files []string `json:"files"`
+}
+
+// Untranslated 计划中需要处理的文件里没有翻译的词, 按文件数量从多到少排列
+func (plan *Plan) Untranslated() []UntranslatedTerm {
+	index := map[string]int{}
+	var terms []UntranslatedTerm
+	for _, item := range plan.Items {
+		if item.Unchanged || item.Error != "" {
+			continue
+		}
+		seen := map[string]bool{}
+		for _, term := range item.Untranslated {
+			if seen[term] {
+				continue
+			}
+			seen[term] = true
+			i, ok := index[term]
+			if !ok {
+				i = len(terms)
+				index[term] = i
+				terms = append(terms, UntranslatedTerm{Term: term})
+			}
+			terms[i].Files = append(terms[i].Files, item.From)
+		}
+	}
+	sort.SliceStable(terms, func(a, b int) bool {
+		if len(terms[a].Files) != len(terms[b].Files) {
+			return len(terms[a].Files) > len(terms[b].Files)
+		}
+		return terms[a].Term < terms[b].Term
+	})
+	return terms
+}
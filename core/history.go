@@ -0,0 +1,176 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 导出历史, 每次导出追加一行, 保存在 DirPath/.chopper/history.jsonl
+const historyFile = "history.jsonl"
+
+// HistoryRecord 一次导出的记录, 路径均相对于 ChopperCfg.DirPath
+type HistoryRecord struct {
+	Time time.Time `json:"time"`
+	Cfg  int64     `json:"cfg"`
+	// 执行导出的人: git 账号, 没有配置 git 时为系统用户
+	User string `json:"user,omitempty"`
+	// 对应的修改日志 ID, 日志撤销后不再存在
+	Journal  string       `json:"journal,omitempty"`
+	Renamed  []Rename     `json:"renamed,omitempty"`
+	Scaled   []string     `json:"scaled,omitempty"`
+	Uploaded []string     `json:"uploaded,omitempty"`
+	Commit   string       `json:"commit,omitempty"`
+	Notified bool         `json:"notified,omitempty"`
+	Errors   []*FileError `json:"errors,omitempty"`
+	// html 报告的路径
+	Report string `json:"report,omitempty"`
+	// 导出中断的原因
+	Error string `json:"error,omitempty"`
+}
+
+func historyPath(cfg ChopperCfg) string {
+	return path.Join(cfg.DirPath, ".chopper", historyFile)
+}
+
+func historyUser(cfg ChopperCfg) string {
+	if cfg.Git.UserName != "" {
+		return cfg.Git.UserName
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+func newHistoryRecord(cfg ChopperCfg, res *Result, err error) HistoryRecord {
+	rec := HistoryRecord{
+		Time:     time.Now(),
+		Cfg:      cfg.ID,
+		User:     historyUser(cfg),
+		Journal:  res.Journal,
+		Renamed:  res.Renamed,
+		Scaled:   res.Scaled,
+		Commit:   res.Commit,
+		Notified: res.Notified,
+		Errors:   res.Errors,
+		Report:   res.Report,
+	}
+	for file := range res.Uploaded {
+		rec.Uploaded = append(rec.Uploaded, file)
+	}
+	sort.Strings(rec.Uploaded)
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	return rec
+}
+
+// appendHistory 追加一条导出记录
+func appendHistory(cfg ChopperCfg, rec HistoryRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	file := historyPath(cfg)
+	err = os.MkdirAll(path.Dir(file), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// LoadHistory 读取配置的导出记录, 最近的在前
+// 同一个文件夹可能被多个配置使用, 只返回 cfg.ID 的记录
+func LoadHistory(cfg ChopperCfg) ([]HistoryRecord, error) {
+	f, err := os.Open(historyPath(cfg))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var records []HistoryRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		var rec HistoryRecord
+		// 写入中途退出导致的不完整行直接忽略
+		if json.Unmarshal(scanner.Bytes(), &rec) != nil {
+			continue
+		}
+		if rec.Cfg == cfg.ID {
+			records = append(records, rec)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records, nil
+}
+
+// Touches 这次导出是否处理了名字中包含 name 的文件, 原文件名和新文件名都会比较
+func (rec *HistoryRecord) Touches(name string) bool {
+	match := func(file string) bool {
+		return strings.Contains(strings.ToLower(file), strings.ToLower(name))
+	}
+	for _, r := range rec.Renamed {
+		if match(r.From) || match(r.To) {
+			return true
+		}
+	}
+	for _, file := range rec.Scaled {
+		if match(file) {
+			return true
+		}
+	}
+	for _, file := range rec.Uploaded {
+		if match(file) {
+			return true
+		}
+	}
+	for _, e := range rec.Errors {
+		if match(e.File) {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary 一行文字摘要: 时间, 执行人, 各项数量和提交
+func (rec *HistoryRecord) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s 改名 %d, 九宫格 %d, 上传 %d, 错误 %d",
+		rec.Time.Format("2006-01-02 15:04:05"), rec.User, len(rec.Renamed), len(rec.Scaled), len(rec.Uploaded), len(rec.Errors))
+	if rec.Commit != "" {
+		commit := rec.Commit
+		if len(commit) > 8 {
+			commit = commit[:8]
+		}
+		b.WriteString(", 提交 " + commit)
+	}
+	if rec.Notified {
+		b.WriteString(", 已通知")
+	}
+	if rec.Error != "" {
+		b.WriteString(", 中断: " + rec.Error)
+	}
+	return b.String()
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path"
+
+	"github.com/disintegration/imaging"
+)
+
+var (
+	ErrorNotFoundImageOptim = errors.New("没有找到压缩工具")
+)
+
+func handle9Scale(file string, left int, top int, right int, bottom int) error {
+	src, err := imaging.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open image: %w", err)
+	}
+
+	src_tl := imaging.CropAnchor(src, left, top, imaging.TopLeft)
+	src_tr := imaging.CropAnchor(src, right, top, imaging.TopRight)
+	src_bl := imaging.CropAnchor(src, left, bottom, imaging.BottomLeft)
+	src_br := imaging.CropAnchor(src, right, bottom, imaging.BottomRight)
+
+	dst := imaging.New(left+right, top+bottom, color.NRGBA{0, 0, 0, 0})
+	dst = imaging.Paste(dst, src_tl, image.Pt(0, 0))
+	dst = imaging.Paste(dst, src_tr, image.Pt(left, 0))
+	dst = imaging.Paste(dst, src_bl, image.Pt(0, top))
+	dst = imaging.Paste(dst, src_br, image.Pt(left, top))
+
+	err = imaging.Save(dst, file)
+	if err != nil {
+		return fmt.Errorf("failed to save image: %w", err)
+	}
+	return nil
+}
+
+func findImageOptim() (string, error) {
+	imageOptim := path.Join("/Applications/ImageOptim.app/Contents/MacOS", "ImageOptim")
+	_, err := os.Stat(imageOptim)
+	if err != nil {
+		return "", ErrorNotFoundImageOptim
+	}
+	return imageOptim, nil
+}
+
+func compressImage(ctx context.Context, images ...string) error {
+	imageOptim, err := findImageOptim()
+	if err != nil {
+		return err
+	}
+	return execute(ctx, imageOptim, images...)
+}
@@ -0,0 +1,292 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 日志中记录的操作
+const (
+	JournalRename    = "rename"
+	JournalOverwrite = "overwrite"
+)
+
+const (
+	journalFile = "journal.json"
+	entriesFile = "entries.jsonl"
+	// 保留最近几次导出的日志, 每份日志都有被覆盖的图片的完整备份
+	journalKeep = 5
+)
+
+var (
+	ErrorNoJournal = errors.New("没有可以撤销的导出记录")
+)
+
+// JournalEntry 一次对目标文件夹的修改, 路径相对于 ChopperCfg.DirPath
+type JournalEntry struct {
+	Op   string `json:"op"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// 被覆盖前的文件内容备份, 相对于日志目录
+	Backup string `json:"backup,omitempty"`
+}
+
+// Journal 一次导出的修改日志, 保存在 DirPath/.chopper/journal/<ID>/ 下, 用于撤销
+type Journal struct {
+	ID      string         `json:"id"`
+	Time    time.Time      `json:"time"`
+	Entries []JournalEntry `json:"-"`
+
+	root    string
+	dir     string
+	entries *os.File
+	mu      sync.Mutex
+	backups int
+}
+
+func journalRoot(cfg ChopperCfg) string {
+	return path.Join(cfg.DirPath, ".chopper", "journal")
+}
+
+func newJournal(cfg ChopperCfg) (*Journal, error) {
+	now := time.Now()
+	j := &Journal{
+		ID:   strconv.FormatInt(now.UnixNano(), 10),
+		Time: now,
+		root: cfg.DirPath,
+	}
+	j.dir = path.Join(journalRoot(cfg), j.ID)
+	err := os.MkdirAll(j.dir, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	err = ioutil.WriteFile(path.Join(j.dir, journalFile), data, 0644)
+	if err != nil {
+		return nil, err
+	}
+	j.entries, err = os.OpenFile(path.Join(j.dir, entriesFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// add 每条记录立即追加写入磁盘, 程序中途退出也可以撤销已完成的部分
+func (j *Journal) add(entry JournalEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err = j.entries.Write(append(data, '\n'))
+	if err != nil {
+		return err
+	}
+	j.Entries = append(j.Entries, entry)
+	return nil
+}
+
+// close 结束记录, 没有任何修改的日志直接删除
+func (j *Journal) close() error {
+	err := j.entries.Close()
+	if err != nil {
+		return err
+	}
+	if len(j.Entries) == 0 {
+		return os.RemoveAll(j.dir)
+	}
+	return nil
+}
+
+// rewrite 用内存中的记录覆盖磁盘上的记录
+func (j *Journal) rewrite() error {
+	var data []byte
+	for _, entry := range j.Entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+	return ioutil.WriteFile(path.Join(j.dir, entriesFile), data, 0644)
+}
+
+func (j *Journal) renamed(from string, to string) error {
+	return j.add(JournalEntry{
+		Op:   JournalRename,
+		From: from,
+		To:   to,
+	})
+}
+
+// backup 在文件被覆盖前备份原内容, 可以并发调用
+func (j *Journal) backup(file string) error {
+	j.mu.Lock()
+	j.backups++
+	backup := path.Join("backup", strconv.Itoa(j.backups))
+	j.mu.Unlock()
+	err := copyFile(path.Join(j.root, file), path.Join(j.dir, backup))
+	if err != nil {
+		return err
+	}
+	return j.add(JournalEntry{
+		Op:     JournalOverwrite,
+		To:     file,
+		Backup: backup,
+	})
+}
+
+// undo 倒序恢复日志中的所有修改, 完成后删除日志
+func (j *Journal) undo() error {
+	marker := undoMarker(j.root)
+	defer touchFile(marker)
+	for i := len(j.Entries) - 1; i >= 0; i-- {
+		entry := j.Entries[i]
+		touchFile(marker)
+		var err error
+		switch entry.Op {
+		case JournalRename:
+			err = os.MkdirAll(path.Dir(path.Join(j.root, entry.From)), os.ModePerm)
+			if err == nil {
+				err = os.Rename(path.Join(j.root, entry.To), path.Join(j.root, entry.From))
+			}
+			if err == nil && path.Dir(entry.From) != path.Dir(entry.To) {
+				removeEmptyDirs(j.root, path.Dir(entry.To))
+			}
+		case JournalOverwrite:
+			err = copyFile(path.Join(j.dir, entry.Backup), path.Join(j.root, entry.To))
+		}
+		if err != nil {
+			// 只保留还没恢复的记录, 修复问题后可以再次撤销剩下的部分
+			j.Entries = j.Entries[:i+1]
+			_ = j.rewrite()
+			return err
+		}
+	}
+	return os.RemoveAll(j.dir)
+}
+
+// journalIDs 所有日志, 从早到晚排列
+func journalIDs(cfg ChopperCfg) ([]string, error) {
+	dirs, err := ioutil.ReadDir(journalRoot(cfg))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, d := range dirs {
+		if d.IsDir() {
+			ids = append(ids, d.Name())
+		}
+	}
+	sort.Slice(ids, func(a, b int) bool {
+		if len(ids[a]) != len(ids[b]) {
+			return len(ids[a]) < len(ids[b])
+		}
+		return ids[a] < ids[b]
+	})
+	return ids, nil
+}
+
+// pruneJournals 导出成功后删除较早的日志, 只保留最近 keep 次
+func pruneJournals(cfg ChopperCfg, keep int) error {
+	ids, err := journalIDs(cfg)
+	if err != nil {
+		return err
+	}
+	for len(ids) > keep {
+		err = os.RemoveAll(path.Join(journalRoot(cfg), ids[0]))
+		if err != nil {
+			return err
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
+
+// LastJournal 读取最近一次导出的修改日志
+func LastJournal(cfg ChopperCfg) (*Journal, error) {
+	ids, err := journalIDs(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, ErrorNoJournal
+	}
+	root := journalRoot(cfg)
+
+	j := &Journal{
+		root: cfg.DirPath,
+		dir:  path.Join(root, ids[len(ids)-1]),
+	}
+	data, err := ioutil.ReadFile(path.Join(j.dir, journalFile))
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, j)
+	if err != nil {
+		return nil, err
+	}
+	data, err = ioutil.ReadFile(path.Join(j.dir, entriesFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		if len(line) == 0 {
+			continue
+		}
+		var entry JournalEntry
+		err = json.Unmarshal(line, &entry)
+		if err != nil {
+			return nil, err
+		}
+		j.Entries = append(j.Entries, entry)
+	}
+	return j, nil
+}
+
+// undoMarker 撤销过程中不断更新这个文件的修改时间, 监视模式据此忽略撤销产生的变动,
+// 撤销和监视不在同一个进程中时也有效
+func undoMarker(root string) string {
+	return path.Join(root, ".chopper", "undo")
+}
+
+func touchFile(file string) {
+	now := time.Now()
+	if err := os.Chtimes(file, now, now); os.IsNotExist(err) {
+		_ = ioutil.WriteFile(file, nil, 0644)
+	}
+}
+
+// undoing 最近 within 时间内是否在撤销 dir 中的导出
+func undoing(dir string, within time.Duration) bool {
+	f, err := os.Stat(undoMarker(dir))
+	return err == nil && time.Since(f.ModTime()) < within
+}
+
+// UndoLast 撤销最近一次导出对目标文件夹的所有修改, 正在导出时返回 ErrorBusy
+func UndoLast(cfg ChopperCfg) (*Journal, error) {
+	if _, busy := exporting.LoadOrStore(cfg.DirPath, true); busy {
+		return nil, ErrorBusy
+	}
+	defer exporting.Delete(cfg.DirPath)
+	j, err := LastJournal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return j, j.undo()
+}
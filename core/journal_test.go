@@ -0,0 +1,64 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+)
+
+// 撤销后恢复原文件名和被九宫格裁剪覆盖的内容
+func TestUndoRestores(t *testing.T) {
+	dir := tempDir(t)
+	writePNG(t, dir, "@背景-天空#(2,2,2,2).png", 10, 10)
+	writePNG(t, dir, "主界面/@按钮-确定.png", 4, 4)
+	cfg := ChopperCfg{DirPath: dir, Dirs: true, Stages: []string{Stage9Scale, StageRename}}
+	res, err := NewExporter(cfg).Run(context.Background())
+	if err != nil || len(res.Errors) > 0 {
+		t.Fatal(err, res.Errors)
+	}
+	if pngWidth(t, dir, "bg_tian1kong1.png") == 10 {
+		t.Fatal("9scale image not cropped")
+	}
+
+	j, err := UndoLast(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(j.Entries) == 0 {
+		t.Fatal("empty journal")
+	}
+	if pngWidth(t, dir, "@背景-天空#(2,2,2,2).png") != 10 || pngWidth(t, dir, "主界面/@按钮-确定.png") != 4 {
+		t.Error("files not restored")
+	}
+	for _, file := range []string{"bg_tian1kong1.png", "zhu3jie4mian4"} {
+		if _, err := os.Stat(path.Join(dir, file)); !os.IsNotExist(err) {
+			t.Errorf("%s left after undo", file)
+		}
+	}
+	if _, err := UndoLast(cfg); err != ErrorNoJournal {
+		t.Errorf("undo again: %v", err)
+	}
+}
+
+// 导出成功后只保留最近几次的日志
+func TestPruneJournals(t *testing.T) {
+	dir := tempDir(t)
+	cfg := ChopperCfg{DirPath: dir, Stages: []string{StageRename}}
+	var last string
+	for run := 0; run < journalKeep+2; run++ {
+		writePNG(t, dir, "@按钮-确定.png", 4, 4)
+		res, err := NewExporter(cfg).Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		last = res.Journal
+	}
+	ids, err := journalIDs(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != journalKeep || ids[len(ids)-1] != last {
+		t.Errorf("journals = %v, want the last %d ending with %s", ids, journalKeep, last)
+	}
+}
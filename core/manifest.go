@@ -0,0 +1,203 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+// ManifestEntry 上次导出后文件的状态
+type ManifestEntry struct {
+	// 导出后的位置, 相对于 ChopperCfg.DirPath
+	Path string `json:"path"`
+	// 导出前的原文件名
+	Origin  string    `json:"origin,omitempty"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	Hash    string    `json:"hash"`
+	// 导出没有全部完成, 只记录了改名前的文件名, 下次导出时仍然需要处理
+	Pending bool `json:"pending,omitempty"`
+}
+
+// Manifest 保存在 DirPath/.chopper/manifest.json, 没有变化的文件在下次导出时跳过
+type Manifest struct {
+	Files map[string]*ManifestEntry `json:"files"`
+
+	mu sync.Mutex
+}
+
+func manifestPath(cfg ChopperCfg) string {
+	return path.Join(cfg.DirPath, ".chopper", "manifest.json")
+}
+
+func loadManifest(cfg ChopperCfg) (*Manifest, error) {
+	m := &Manifest{Files: map[string]*ManifestEntry{}}
+	data, err := ioutil.ReadFile(manifestPath(cfg))
+	if os.IsNotExist(err) {
+		return m, nil
+	} else if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, m)
+	if err != nil {
+		return nil, err
+	}
+	if m.Files == nil {
+		m.Files = map[string]*ManifestEntry{}
+	}
+	return m, nil
+}
+
+func (m *Manifest) save(cfg ChopperCfg) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(path.Dir(manifestPath(cfg)), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(manifestPath(cfg), data, 0644)
+}
+
+func fileHash(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// unchanged 文件和上次导出后相比是否没有变化
+// 大小和修改时间相同时直接认为没有变化, 否则比较内容 hash, 可以并发调用
+func (m *Manifest) unchanged(cfg ChopperCfg, file string) bool {
+	m.mu.Lock()
+	entry, ok := m.Files[file]
+	m.mu.Unlock()
+	if !ok || entry.Pending {
+		return false
+	}
+	info, err := os.Stat(path.Join(cfg.DirPath, file))
+	if err != nil {
+		return false
+	}
+	if info.Size() == entry.Size && info.ModTime().Equal(entry.ModTime) {
+		return true
+	}
+	if info.Size() != entry.Size {
+		return false
+	}
+	hash, err := fileHash(path.Join(cfg.DirPath, file))
+	if err != nil || hash != entry.Hash {
+		return false
+	}
+	m.mu.Lock()
+	entry.ModTime = info.ModTime()
+	m.mu.Unlock()
+	return true
+}
+
+// origin 已经导出并改过名的文件第一次导出前的文件名, 其他文件返回空
+func (m *Manifest) origin(file string) string {
+	entry, ok := m.Files[file]
+	if !ok || entry.Origin == file {
+		return ""
+	}
+	return entry.Origin
+}
+
+// record 记录导出后文件的状态, 可以并发调用
+func (m *Manifest) record(cfg ChopperCfg, file string, origin string) error {
+	info, err := os.Stat(path.Join(cfg.DirPath, file))
+	if err != nil {
+		return err
+	}
+	hash, err := fileHash(path.Join(cfg.DirPath, file))
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := &ManifestEntry{
+		Path:    file,
+		Origin:  origin,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Hash:    hash,
+	}
+	if old, ok := m.Files[file]; ok && old.Origin != "" && origin == file {
+		// 已经改过名的文件保留最初的原文件名
+		entry.Origin = old.Origin
+	}
+	m.Files[file] = entry
+	return nil
+}
+
+// pending 记录已经改名但没有完成导出的文件, 下次导出时沿用现在的名字
+func (m *Manifest) pending(file string, origin string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if old, ok := m.Files[file]; ok && old.Origin != "" && origin == file {
+		origin = old.Origin
+	}
+	m.Files[file] = &ManifestEntry{Path: file, Origin: origin, Pending: true}
+}
+
+// updateManifest 记录导出后的文件, 并移除已经不存在的文件
+// done 为 true 时全部阶段都已完成, 记录所有处理成功的文件; 否则只记录已经改名的文件, 它们下次导出时仍然需要处理
+func (job *Job) updateManifest(ctx context.Context, done bool) error {
+	if job.manifest == nil {
+		return nil
+	}
+	m := job.manifest
+	current := map[string]bool{}
+	for _, file := range job.Files {
+		current[file] = true
+	}
+	for file := range m.Files {
+		if current[file] {
+			continue
+		}
+		// 被重新导出的文件替换掉的文件不在计划中, 但仍然存在
+		if _, err := os.Stat(path.Join(job.Cfg.DirPath, file)); os.IsNotExist(err) {
+			delete(m.Files, file)
+		}
+	}
+	var indexes []int
+	for i, item := range job.Plan.Items {
+		if done && job.Processing(i) {
+			indexes = append(indexes, i)
+		} else if job.Files[i] != item.From {
+			// 改名后失败的文件也要记录, 否则下次导出会按现在的名字再改一次
+			m.pending(job.Files[i], item.From)
+		}
+	}
+	errs := make([]error, len(indexes))
+	err := parallel(ctx, job.Workers(), len(indexes), func(n int) {
+		i := indexes[n]
+		errs[n] = m.record(job.Cfg, job.Files[i], job.Plan.Items[i].From)
+	})
+	if err != nil {
+		return err
+	}
+	for n, i := range indexes {
+		if errs[n] != nil {
+			// 没有记录的文件下次导出时会重新处理
+			job.Fail(i, StageManifest, errs[n])
+		}
+	}
+	return m.save(job.Cfg)
+}
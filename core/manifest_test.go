@@ -0,0 +1,90 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestManifestSkipsUnchanged(t *testing.T) {
+	dir := tempDir(t)
+	writePNG(t, dir, "@按钮-确定.png", 4, 4)
+	writePNG(t, dir, "@背景-天空.png", 4, 4)
+	cfg := ChopperCfg{DirPath: dir, Stages: []string{StageRename}}
+	e := NewExporter(cfg)
+	if _, err := e.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	changed := func() int {
+		t.Helper()
+		plan, err := e.Plan(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return plan.Changed()
+	}
+	if n := changed(); n != 0 {
+		t.Fatalf("%d files changed right after export", n)
+	}
+
+	// 只有修改时间变化, 内容相同
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path.Join(dir, "bg_tian1kong1.png"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if n := changed(); n != 0 {
+		t.Errorf("%d files changed after touch", n)
+	}
+
+	writePNG(t, dir, "bg_tian1kong1.png", 8, 8)
+	if n := changed(); n != 1 {
+		t.Errorf("%d files changed after edit, want 1", n)
+	}
+
+	e.Full = true
+	if n := changed(); n != 2 {
+		t.Errorf("%d files changed in a full export, want 2", n)
+	}
+}
+
+type failStage struct{}
+
+func (failStage) Name() string {
+	return "test-fail"
+}
+
+func (failStage) Run(ctx context.Context, job *Job) error {
+	return errors.New("上传失败")
+}
+
+// 后面的阶段失败时已经改名的文件也要记录, 重试时不能再改名
+func TestManifestAfterFailedStage(t *testing.T) {
+	RegisterStage(failStage{})
+	dir := tempDir(t)
+	writePNG(t, dir, "ui/@按钮-确定.png", 4, 4)
+	cfg := ChopperCfg{
+		DirPath:  dir,
+		Template: "{dir}_{type}_{name}",
+		Stages:   []string{StageRename, failStage{}.Name()},
+	}
+	e := NewExporter(cfg)
+	for run := 0; run < 3; run++ {
+		plan, err := e.Plan(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 没有完成的文件仍然需要处理
+		if plan.Changed() != 1 {
+			t.Fatalf("run %d: %d files changed, want 1", run, plan.Changed())
+		}
+		if _, err := e.Apply(context.Background(), plan); err == nil {
+			t.Fatal("stage did not fail")
+		}
+		if _, err := os.Stat(path.Join(dir, "ui/ui_btn_que4ding4.png")); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MappingEntry 导出后的文件和原文件名的对照
+type MappingEntry struct {
+	// 原文件, 相对于资源目录, 例如 主界面/@按钮-返回.png
+	Origin string `json:"origin"`
+	// 导出后的文件, 相对于资源目录和 .remote
+	Path        string    `json:"path"`
+	FirstExport time.Time `json:"first_export"`
+	LastExport  time.Time `json:"last_export"`
+	// 文件内容的 sha256
+	Hash string `json:"hash"`
+}
+
+// Matches 原文件名或导出后的文件名中是否包含 name, 不区分大小写
+func (e *MappingEntry) Matches(name string) bool {
+	name = strings.ToLower(name)
+	return strings.Contains(strings.ToLower(e.Origin), name) || strings.Contains(strings.ToLower(e.Path), name)
+}
+
+// MappingPath 文件名对照表, 保存在 .remote 中和资源一起提交, 团队成员都可以查到
+func MappingPath(cfg ChopperCfg) string {
+	return path.Join(cfg.DirPath, ".remote", ".chopper", "names.json")
+}
+
+// LoadMapping 读取文件名对照表, 按导出后的文件排列, 还没有上传过时为空
+func LoadMapping(cfg ChopperCfg) ([]MappingEntry, error) {
+	return loadMapping(MappingPath(cfg))
+}
+
+func loadMapping(file string) ([]MappingEntry, error) {
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []MappingEntry
+	err = json.Unmarshal(data, &entries)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// SearchMapping 按原文件名或导出后的文件名查找
+func SearchMapping(entries []MappingEntry, name string) []MappingEntry {
+	var found []MappingEntry
+	for i := range entries {
+		if entries[i].Matches(name) {
+			found = append(found, entries[i])
+		}
+	}
+	return found
+}
+
+// updateMapping 在 .remote 中记录本次上传的文件, 移除改名前的旧文件
+// uploaded 为上传的文件 -> 原文件, 需要在文件拷贝到 .remote 之后调用
+func updateMapping(remote string, uploaded map[string]string, removed []string, now time.Time) error {
+	file := path.Join(remote, ".chopper", "names.json")
+	entries, err := loadMapping(file)
+	if err != nil {
+		return err
+	}
+	byPath := map[string]MappingEntry{}
+	byOrigin := map[string]MappingEntry{}
+	for _, e := range entries {
+		byPath[e.Path] = e
+		byOrigin[e.Origin] = e
+	}
+	for _, f := range removed {
+		if _, ok := uploaded[f]; !ok {
+			delete(byPath, f)
+		}
+	}
+	for f, origin := range uploaded {
+		hash, err := fileHash(path.Join(remote, f))
+		if err != nil {
+			return err
+		}
+		e, ok := byPath[f]
+		if !ok || e.Origin != origin {
+			e = MappingEntry{Origin: origin, Path: f, FirstExport: now}
+			// 改名规则变化后, 同一个原文件保留最初的导出时间
+			if old, ok := byOrigin[origin]; ok {
+				e.FirstExport = old.FirstExport
+			}
+		}
+		e.LastExport = now
+		e.Hash = hash
+		byPath[f] = e
+	}
+
+	entries = entries[:0]
+	for _, e := range byPath {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].Path < entries[b].Path
+	})
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(path.Dir(file), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
@@ -0,0 +1,500 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mozillazg/go-pinyin"
+)
+
+var (
+	ErrorUnknownPinyinStyle = errors.New("未知的拼音风格")
+	ErrorUnknownCaseStyle   = errors.New("未知的命名风格")
+)
+
+// 拼音风格, 对应 go-pinyin 的 Style
+var pinyinStyles = map[string]int{
+	"normal":       pinyin.Normal,
+	"tone":         pinyin.Tone,
+	"tone2":        pinyin.Tone2,
+	"tone3":        pinyin.Tone3,
+	"initials":     pinyin.Initials,
+	"first_letter": pinyin.FirstLetter,
+	"finals":       pinyin.Finals,
+	"finals_tone":  pinyin.FinalsTone,
+	"finals_tone2": pinyin.FinalsTone2,
+	"finals_tone3": pinyin.FinalsTone3,
+}
+
+// PinyinStyles 可以配置的拼音风格, 默认 tone3
+var PinyinStyles = []string{"normal", "tone", "tone2", "tone3", "initials", "first_letter", "finals", "finals_tone", "finals_tone2", "finals_tone3"}
+
+// 词之间的连接方式和大小写
+const (
+	// CaseKeep 保持原样: 前缀标签加 _, 其余部分直接拼接, 不改变大小写
+	CaseKeep = "keep"
+	// CaseSnake 小写, 词之间用 _ 连接, 例如 btn_queding_ok
+	CaseSnake = "snake"
+	// CaseCamel 第一个词小写, 之后每个词和音节首字母大写, 例如 btnQueDingOk
+	CaseCamel = "camel"
+	// CaseKebab 小写, 词之间用 - 连接, 例如 btn-queding-ok
+	CaseKebab = "kebab"
+)
+
+var CaseStyles = []string{CaseKeep, CaseSnake, CaseCamel, CaseKebab}
+
+var (
+	regType   = regexp.MustCompile(`^@.+?-`)
+	reg9Scale = regexp.MustCompile(`#\([\d|,]+\)`)
+)
+
+// PrefixTag 文件名前缀 @<Prefix>- 替换为类型标签 <Tag>, 例如 @按钮-确定.png -> btn_que4ding4.png
+type PrefixTag struct {
+	Prefix string `json:"prefix"`
+	Tag    string `json:"tag"`
+}
+
+// DefaultPrefixes 没有配置前缀表时使用
+var DefaultPrefixes = []PrefixTag{
+	{Prefix: "按钮", Tag: "btn"},
+	{Prefix: "背景", Tag: "bg"},
+	{Prefix: "图标", Tag: "icon"},
+	{Prefix: "预览", Tag: "preview"},
+	{Prefix: "动画", Tag: "ani"},
+}
+
+// CfgPrefixes 配置使用的前缀表
+func CfgPrefixes(cfg ChopperCfg) []PrefixTag {
+	if cfg.Prefixes == nil {
+		return DefaultPrefixes
+	}
+	return cfg.Prefixes
+}
+
+func newPinyinArgs(style string) (pinyin.Args, error) {
+	pyArgs := pinyin.NewArgs()
+	pyArgs.Style = pinyin.Tone3
+	if style != "" {
+		value, ok := pinyinStyles[style]
+		if !ok {
+			return pyArgs, fmt.Errorf("%w: %s", ErrorUnknownPinyinStyle, style)
+		}
+		pyArgs.Style = value
+	}
+	// 不在拼音表中的字符保持原样
+	pyArgs.Fallback = func(r rune, a pinyin.Args) []string {
+		return nil
+	}
+	return pyArgs, nil
+}
+
+func parse9Scale(scaleTag string) []int {
+	scaleStrs := strings.Split(scaleTag, ",")
+	var scaleNums []int
+	for _, s := range scaleStrs {
+		num, err := strconv.Atoi(s)
+		if err == nil {
+			scaleNums = append(scaleNums, num)
+		}
+	}
+	var left, top, right, bottom int
+	if len(scaleNums) == 0 {
+		return nil
+	} else if len(scaleNums) == 1 {
+		left, top, right, bottom = scaleNums[0], scaleNums[0], scaleNums[0], scaleNums[0]
+	} else if len(scaleNums) == 2 {
+		left, right = scaleNums[0], scaleNums[0]
+		top, bottom = scaleNums[1], scaleNums[1]
+	} else if len(scaleNums) == 3 {
+		left = scaleNums[0]
+		top, bottom = scaleNums[1], scaleNums[1]
+		right = scaleNums[2]
+	} else {
+		left = scaleNums[0]
+		top = scaleNums[1]
+		right = scaleNums[2]
+		bottom = scaleNums[3]
+	}
+	return []int{left, top, right, bottom}
+}
+
+// namer 按配置计算文件导出后的名字
+type namer struct {
+	pyArgs    pinyin.Args
+	separator string
+	caseStyle string
+	lowercase bool
+	// 前缀 -> 类型标签
+	prefixes map[string]string
+	dict     *dict
+	// 转换文件夹名, 同一个文件夹只计算一次
+	dirs     bool
+	dirNames map[string]dirName
+	// 为空时使用默认的命名方式
+	template *nameTemplate
+	// 倍图后缀的形式, 见 RetinaCfg
+	retina    string
+	retinaReg *regexp.Regexp
+	// 使用词汇表翻译, 见 BackendGlossary
+	glossary bool
+}
+
+// dirName 文件夹转换后的路径
+type dirName struct {
+	to           string
+	overrides    []string
+	untranslated []string
+	err          string
+}
+
+func newNamer(cfg ChopperCfg) (*namer, error) {
+	pyArgs, err := newPinyinArgs(cfg.PinyinStyle)
+	if err != nil {
+		return nil, err
+	}
+	n := &namer{
+		pyArgs:    pyArgs,
+		separator: cfg.Separator,
+		caseStyle: cfg.CaseStyle,
+		lowercase: cfg.Validation.Lowercase,
+		prefixes:  map[string]string{},
+		dirs:      cfg.Dirs,
+		dirNames:  map[string]dirName{},
+	}
+	if n.caseStyle == "" {
+		n.caseStyle = CaseKeep
+	}
+	found := false
+	for _, c := range CaseStyles {
+		found = found || c == n.caseStyle
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrorUnknownCaseStyle, n.caseStyle)
+	}
+	for _, p := range CfgPrefixes(cfg) {
+		n.prefixes[normalizeName(p.Prefix)] = p.Tag
+	}
+	overrides, err := LoadOverrides(cfg)
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.Backend {
+	case "", BackendPinyin:
+	case BackendGlossary:
+		glossary, err := LoadGlossary(cfg)
+		if err != nil {
+			return nil, err
+		}
+		n.glossary = true
+		overrides = append(glossaryOverrides(glossary), overrides...)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrorUnknownBackend, cfg.Backend)
+	}
+	n.dict = newDict(overrides)
+	n.retina, err = newRetinaSuffix(cfg.Retina)
+	if err != nil {
+		return nil, err
+	}
+	n.retinaReg = retinaRegexp(n.retina)
+	if cfg.Template != "" {
+		n.template, err = parseTemplate(cfg.Template)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// word 名字中的一个词: 一段连续的汉字, 按音节拆开; 或者一段其他字符
+type word struct {
+	parts  []string
+	pinyin bool
+	// 词典或词汇表中的英文单词
+	english bool
+}
+
+// words 把名字拆成词, 汉字转为拼音, 空格去掉, 返回用到的词典中的词和使用了拼音的词
+func (n *namer) words(name string) ([]word, []string, []string) {
+	var words []word
+	var fired []string
+	var untranslated []string
+	var text []rune
+	// 词汇表中没有的一段连续的汉字
+	var term []rune
+	flushText := func() {
+		if len(text) > 0 {
+			words = append(words, word{parts: []string{string(text)}})
+			text = nil
+		}
+	}
+	flushTerm := func() {
+		if len(term) > 0 {
+			untranslated = append(untranslated, string(term))
+			term = nil
+		}
+	}
+	flush := func() {
+		flushText()
+		flushTerm()
+	}
+	// 连续的拼音合并为一个词
+	addPinyin := func(syllables ...string) {
+		if len(words) > 0 && words[len(words)-1].pinyin {
+			last := &words[len(words)-1]
+			last.parts = append(last.parts, syllables...)
+		} else {
+			words = append(words, word{parts: syllables, pinyin: true})
+		}
+	}
+	runes := []rune(name)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == ' ' {
+			flush()
+			continue
+		}
+		// 词典优先于拼音库
+		if o, length := n.dict.match(runes[i:]); o != nil {
+			flush()
+			if !o.glossary {
+				fired = append(fired, o.Phrase)
+			}
+			phrase := runes[i : i+length]
+			i += length - 1
+			if o.English != "" {
+				for _, w := range strings.Fields(o.English) {
+					words = append(words, word{parts: []string{w}, english: true})
+				}
+				continue
+			}
+			syllables := strings.Fields(o.Pinyin)
+			for j := range syllables {
+				// 读音和汉字一一对应时按汉字的多音字读音转换风格
+				if len(syllables) == len(phrase) {
+					syllables[j] = overrideSyllable(phrase[j], syllables[j], n.pyArgs)
+				}
+			}
+			addPinyin(syllables...)
+			continue
+		}
+		var py []string
+		if unicode.Is(unicode.Han, r) {
+			py = pinyin.SinglePinyin(r, n.pyArgs)
+		}
+		if len(py) == 0 {
+			flushTerm()
+			text = append(text, r)
+			continue
+		}
+		flushText()
+		if n.glossary {
+			term = append(term, r)
+		}
+		addPinyin(py[0])
+	}
+	flush()
+	return words, fired, untranslated
+}
+
+// splitText 按字母和数字以外的字符拆开, 用于需要统一连接符的命名风格
+func splitText(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func upperFirst(s string) string {
+	for i, r := range s {
+		return string(unicode.ToUpper(r)) + s[i+len(string(r)):]
+	}
+	return s
+}
+
+func lowerFirst(s string) string {
+	for i, r := range s {
+		return string(unicode.ToLower(r)) + s[i+len(string(r)):]
+	}
+	return s
+}
+
+// convert 把前缀标签和名字 (不含扩展名) 按拼音风格, 分隔符和命名风格转换, 返回用到的词典中的词和使用了拼音的词
+func (n *namer) convert(tag string, name string) (string, []string, []string) {
+	words, fired, untranslated := n.words(name)
+	if n.caseStyle == CaseKeep {
+		var b strings.Builder
+		if tag != "" {
+			b.WriteString(tag + "_")
+		}
+		for i, w := range words {
+			// 使用词汇表时英文单词之间, 英文和拼音之间用 _ 隔开
+			if n.glossary && i > 0 && (w.english || w.pinyin) && (words[i-1].english || words[i-1].pinyin) {
+				b.WriteString("_")
+			}
+			if w.pinyin {
+				b.WriteString(strings.Join(w.parts, n.separator))
+			} else {
+				b.WriteString(w.parts[0])
+			}
+		}
+		return b.String(), fired, untranslated
+	}
+
+	var result []string
+	if tag != "" {
+		result = append(result, splitText(tag)...)
+	}
+	for _, w := range words {
+		if !w.pinyin {
+			result = append(result, splitText(w.parts[0])...)
+			continue
+		}
+		parts := w.parts
+		if n.caseStyle == CaseCamel {
+			parts = make([]string, len(w.parts))
+			for i, p := range w.parts {
+				parts[i] = upperFirst(p)
+			}
+		}
+		result = append(result, strings.Join(parts, n.separator))
+	}
+	switch n.caseStyle {
+	case CaseCamel:
+		for i := range result {
+			if i == 0 {
+				result[i] = lowerFirst(result[i])
+			} else {
+				result[i] = upperFirst(result[i])
+			}
+		}
+		return strings.Join(result, ""), fired, untranslated
+	case CaseKebab:
+		return strings.ToLower(strings.Join(result, "-")), fired, untranslated
+	default:
+		return strings.ToLower(strings.Join(result, "_")), fired, untranslated
+	}
+}
+
+// prefixTag 拆出名字开头的 @<前缀>-, 返回类型标签和剩下的部分
+func (n *namer) prefixTag(name string) (string, string, error) {
+	loc := regType.FindStringIndex(name)
+	if len(loc) == 0 {
+		return "", name, nil
+	}
+	prefix := name[1 : loc[1]-1]
+	tag, ok := n.prefixes[prefix]
+	if !ok {
+		return "", name, errors.New("未知的前缀: @" + prefix + "-")
+	}
+	return tag, name[loc[1]:], nil
+}
+
+// planDir 按文件名的规则逐级转换文件夹名
+func (n *namer) planDir(dir string) dirName {
+	if !n.dirs || dir == "." {
+		return dirName{to: dir}
+	}
+	if d, ok := n.dirNames[dir]; ok {
+		return d
+	}
+	d := n.planDir(path.Dir(dir))
+	if d.err == "" {
+		d.overrides = append([]string(nil), d.overrides...)
+		d.untranslated = append([]string(nil), d.untranslated...)
+		tag, name, err := n.prefixTag(normalizeName(path.Base(dir)))
+		if err != nil {
+			d.err = err.Error()
+		} else {
+			name, fired, untranslated := n.convert(tag, name)
+			if n.lowercase {
+				name = strings.ToLower(name)
+			}
+			d.to = path.Join(d.to, name)
+			d.overrides = append(d.overrides, fired...)
+			d.untranslated = append(d.untranslated, untranslated...)
+		}
+	}
+	n.dirNames[dir] = d
+	return d
+}
+
+func (n *namer) planFile(file string) PlanItem {
+	fileName := path.Base(file)
+	// 只用于计算需要转换的文件的新名字, 其他文件保持原样
+	normalized := normalizeName(fileName)
+	ext := path.Ext(normalized)
+	item := PlanItem{From: file, To: file}
+	dir := n.planDir(path.Dir(file))
+	if dir.err != "" {
+		item.Error = dir.err
+		return item
+	}
+	fileDir := dir.to
+	item.To = path.Join(fileDir, fileName)
+	item.Overrides = append([]string(nil), dir.overrides...)
+	item.Untranslated = append([]string(nil), dir.untranslated...)
+	if ext == ".png" || ext == ".jpg" {
+		item.converted = true
+		targetName := strings.TrimSuffix(normalized, ext)
+
+		// sketch 导出的倍图, 例如 开始@2x, 倍数后缀不参与转换, 按配置的形式加在最后
+		targetName, item.Retina = parseRetina(targetName, n.retinaReg)
+
+		// 替换前缀类型, 例如 @按钮- -> btn, 前缀表中没有的前缀不处理这个文件
+		tag, targetName, err := n.prefixTag(targetName)
+		if err != nil {
+			item.To = file
+			item.Error = err.Error()
+			return item
+		}
+
+		// 处理九宫格图片
+		loc := reg9Scale.FindStringIndex(targetName)
+		if len(loc) > 0 {
+			// 去掉 #( )
+			item.Scale = parse9Scale(targetName[loc[0]+2 : loc[1]-1])
+			targetName = targetName[:loc[0]] + targetName[loc[1]:]
+		}
+		if n.template != nil {
+			n.templateParts(&item, fileDir, tag, targetName, ext)
+			return item
+		}
+		name, fired, untranslated := n.convert(tag, targetName)
+		item.To = path.Join(fileDir, name+n.retinaSuffix(item.Retina)+ext)
+		item.variant = path.Join(fileDir, name) + ext
+		item.Overrides = append(item.Overrides, fired...)
+		item.Untranslated = append(item.Untranslated, untranslated...)
+	} else if ext == ".mp3" || ext == ".ogg" || ext == ".m4a" {
+		item.converted = true
+		if n.template != nil {
+			n.templateParts(&item, fileDir, "", strings.TrimSuffix(normalized, ext), ext)
+			return item
+		}
+		name, fired, untranslated := n.convert("", strings.TrimSuffix(normalized, ext))
+		item.To = path.Join(fileDir, name+ext)
+		item.Overrides = append(item.Overrides, fired...)
+		item.Untranslated = append(item.Untranslated, untranslated...)
+	}
+	// 只改需要转换的文件, 其他文件可能被按原名引用
+	if n.lowercase && item.converted {
+		item.To = path.Join(fileDir, strings.ToLower(path.Base(item.To)))
+	}
+	return item
+}
+
+// templateParts 记录命名模板需要的各部分, 新文件名在所有文件计算完后由 renderTemplate 得到
+func (n *namer) templateParts(item *PlanItem, dir string, tag string, name string, ext string) {
+	parts := &nameParts{dir: dir, tag: tag, ext: ext}
+	var fired, untranslated []string
+	parts.name, fired, untranslated = n.convert("", name)
+	if ext == ".png" || ext == ".jpg" {
+		item.variant = path.Join(dir, tag+"_"+parts.name) + ext
+	}
+	item.Overrides = append(item.Overrides, fired...)
+	item.Untranslated = append(item.Untranslated, untranslated...)
+	item.parts = parts
+}
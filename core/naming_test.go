@@ -0,0 +1,69 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+type planFileCase struct {
+	name   string
+	cfg    ChopperCfg
+	file   string
+	to     string
+	scale  []int
+	retina int
+	err    bool
+}
+
+// testPlanFile 检查 namer.planFile 的结果, 并且按新文件名再计算一次不会再改名
+func testPlanFile(t *testing.T, tests []planFileCase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := newNamer(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			item := n.planFile(tt.file)
+			if item.To != tt.to || len(item.Scale) != len(tt.scale) || len(tt.scale) > 0 && !reflect.DeepEqual(item.Scale, tt.scale) ||
+				item.Retina != tt.retina || (item.Error != "") != tt.err {
+				t.Fatalf("planFile(%s) = %s %v @%dx %q", tt.file, item.To, item.Scale, item.Retina, item.Error)
+			}
+			if tt.err {
+				return
+			}
+			again := n.planFile(item.To)
+			if again.To != item.To || again.Retina != item.Retina || again.Error != "" {
+				t.Errorf("planFile(%s) = %s %q, want unchanged", item.To, again.To, again.Error)
+			}
+		})
+	}
+}
+
+func TestPlanFile(t *testing.T) {
+	testPlanFile(t, []planFileCase{
+		{"prefix", ChopperCfg{}, "@按钮-确定.png", "btn_que4ding4.png", nil, 0, false},
+		{"unknown prefix", ChopperCfg{}, "@未知-确定.png", "@未知-确定.png", nil, 0, true},
+		{"no prefix", ChopperCfg{}, "确 定.png", "que4ding4.png", nil, 0, false},
+		{"other type", ChopperCfg{}, "说明.txt", "说明.txt", nil, 0, false},
+		{"audio", ChopperCfg{}, "音效/点击.mp3", "音效/dian3ji1.mp3", nil, 0, false},
+		{"snake", ChopperCfg{CaseStyle: CaseSnake}, "@按钮-确定.png", "btn_que4ding4.png", nil, 0, false},
+		{"camel", ChopperCfg{CaseStyle: CaseCamel}, "@背景-天空.png", "bgTian1Kong1.png", nil, 0, false},
+		{"kebab", ChopperCfg{CaseStyle: CaseKebab}, "@图标-金币.png", "icon-jin1bi4.png", nil, 0, false},
+		{"pinyin normal", ChopperCfg{PinyinStyle: "normal"}, "@按钮-确定.png", "btn_queding.png", nil, 0, false},
+		{"9scale", ChopperCfg{}, "@背景-天空#(1,2,3,4).png", "bg_tian1kong1.png", []int{1, 2, 3, 4}, 0, false},
+	})
+}
+
+func TestPlanFileDirs(t *testing.T) {
+	dirs := ChopperCfg{Dirs: true, CaseStyle: CaseSnake}
+	testPlanFile(t, []planFileCase{
+		{"dirs", dirs, "主界面/@按钮-确定@2x.png", "zhu3jie4mian4/btn_que4ding4@2x.png", nil, 2, false},
+		{"nested", dirs, "主界面/设置/@按钮-确定.png", "zhu3jie4mian4/she4zhi4/btn_que4ding4.png", nil, 0, false},
+		{"case style", dirs, "UI/确定.png", "ui/que4ding4.png", nil, 0, false},
+		// 不需要转换的文件跟随文件夹移动, 文件名不变
+		{"other type", dirs, "主界面/说明.txt", "zhu3jie4mian4/说明.txt", nil, 0, false},
+		{"unknown prefix", dirs, "@未知-界面/确定.png", "@未知-界面/确定.png", nil, 0, true},
+		{"off", ChopperCfg{CaseStyle: CaseSnake}, "主界面/@按钮-确定.png", "主界面/btn_que4ding4.png", nil, 0, false},
+	})
+}
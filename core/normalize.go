@@ -0,0 +1,14 @@
+package core
+
+import (
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+)
+
+// normalizeName 统一文件名的写法, 看起来相同的名字得到相同的结果:
+// macOS 同步过来的文件名常为分解形式 (NFD), 统一为组合形式 (NFC);
+// 全角的字母, 数字和符号 (例如 １２, Ａ, ＠, （）) 转为半角;
+// 繁体字转为简体字, 见 t2sTable
+func normalizeName(name string) string {
+	return simplified(width.Fold.String(norm.NFC.String(name)))
+}
@@ -0,0 +1,42 @@
+package core
+
+import "testing"
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		// macOS 的分解形式
+		{"cafe\u0301.png", "caf\u00e9.png"},
+		{"ｂｔｎ＿ＯＫ１２.png", "btn_OK12.png"},
+		{"＠按钮－确定（大）.png", "@按钮-确定(大).png"},
+		{"确定.png", "确定.png"},
+	}
+	for _, tt := range tests {
+		if got := normalizeName(tt.name); got != tt.want {
+			t.Errorf("normalizeName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// 写法不同但看起来相同的文件名得到相同的新文件名, 原文件名保持不变
+func TestPlanFileNormalized(t *testing.T) {
+	testPlanFile(t, []planFileCase{
+		{"full-width prefix", ChopperCfg{}, "＠按钮－确定.png", "btn_que4ding4.png", nil, 0, false},
+		{"full-width 9scale", ChopperCfg{}, "@背景-天空＃（１，２，３，４）.png", "bg_tian1kong1.png", []int{1, 2, 3, 4}, 0, false},
+		{"full-width retina", ChopperCfg{}, "@按钮-确定＠２ｘ.png", "btn_que4ding4@2x.png", nil, 2, false},
+		// 不需要转换的文件保持原名
+		{"other type", ChopperCfg{}, "ｒｅａｄｍｅ.txt", "ｒｅａｄｍｅ.txt", nil, 0, false},
+	})
+
+	n, err := newNamer(ChopperCfg{Overrides: []Override{{Phrase: "café", English: "coffee"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"cafe\u0301.png", "caf\u00e9.png"} {
+		if item := n.planFile(file); item.From != file || item.To != "coffee.png" {
+			t.Errorf("planFile(%q) = %q -> %q", file, item.From, item.To)
+		}
+	}
+}
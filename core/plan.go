@@ -0,0 +1,236 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+)
+
+// git 变动类型, 与本地 .remote 仓库中的文件比较得出
+const (
+	GitAdded    = "added"
+	GitModified = "modified"
+)
+
+// PlanItem 一个文件计划进行的处理, 路径相对于 ChopperCfg.DirPath
+type PlanItem struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// 九宫格裁剪: left, top, right, bottom
+	Scale []int `json:"scale,omitempty"`
+	// 倍图的倍数, 例如 @2x 为 2, 没有倍数后缀时为 0
+	Retina int    `json:"retina,omitempty"`
+	Git    string `json:"git,omitempty"`
+	// 和上次导出后相比没有变化, 不需要处理
+	Unchanged bool `json:"unchanged,omitempty"`
+	// 计算新文件名时用到的词典中的词
+	Overrides []string `json:"overrides,omitempty"`
+	// 使用词汇表时, 词汇表中没有而使用了拼音的词
+	Untranslated []string `json:"untranslated,omitempty"`
+	// 文件名无法处理的原因, 这个文件不会被导出
+	Error string `json:"error,omitempty"`
+	// 新文件名不符合检查规则, 但仍然会导出
+	Warnings []string `json:"warnings,omitempty"`
+
+	// 按命名模板计算文件名需要的信息
+	parts *nameParts
+	// 是需要转换文件名的文件类型, 新文件名应该只有 ASCII 字符
+	converted bool
+	// 已经导出并改过名的文件第一次导出前的文件名, 这样的文件沿用之前导出的名字
+	origin string
+	// 图片转换后去掉倍数后缀的名字, 同一张图片的各倍图相同
+	variant string
+	// 重新导出已经导出过的原文件, 替换之前导出的文件
+	replaces bool
+}
+
+func (item *PlanItem) Renamed() bool {
+	return item.From != item.To
+}
+
+// Plan 导出计划, 生成时不修改任何文件
+type Plan struct {
+	Items     []PlanItem `json:"items"`
+	Conflicts []Conflict `json:"conflicts,omitempty"`
+
+	manifest *Manifest
+}
+
+// Changed 需要处理的文件数量
+func (plan *Plan) Changed() int {
+	count := 0
+	for _, item := range plan.Items {
+		if !item.Unchanged {
+			count++
+		}
+	}
+	return count
+}
+
+// Invalid 文件名无法处理的文件数量
+func (plan *Plan) Invalid() int {
+	count := 0
+	for _, item := range plan.Items {
+		if item.Error != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// Warned 新文件名有警告的文件数量
+func (plan *Plan) Warned() int {
+	count := 0
+	for _, item := range plan.Items {
+		if len(item.Warnings) > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// gitChange 对比本地 .remote 仓库, 得出文件上传后的变动类型
+func gitChange(cfg ChopperCfg, item PlanItem) string {
+	if item.Scale != nil {
+		// 裁剪后内容一定会变化, 无法预先比较
+		if _, err := os.Stat(path.Join(cfg.DirPath, ".remote", item.To)); err != nil {
+			return GitAdded
+		}
+		return GitModified
+	}
+	remote, err := ioutil.ReadFile(path.Join(cfg.DirPath, ".remote", item.To))
+	if err != nil {
+		return GitAdded
+	}
+	local, err := ioutil.ReadFile(path.Join(cfg.DirPath, item.From))
+	if err != nil || !bytes.Equal(local, remote) {
+		return GitModified
+	}
+	return ""
+}
+
+// replaceExported 已经导出的原文件再次出现时 (例如 Sketch 重新导出了切图), 作为之前导出的文件的更新,
+// 沿用之前导出的文件名并替换它, 而不是和它冲突
+func replaceExported(plan *Plan) {
+	exported := map[string]int{}
+	for i, item := range plan.Items {
+		if item.origin != "" {
+			exported[item.origin] = i
+		}
+	}
+	replaced := map[int]bool{}
+	for i := range plan.Items {
+		item := &plan.Items[i]
+		if item.origin != "" || item.Error != "" {
+			continue
+		}
+		j, ok := exported[item.From]
+		if !ok {
+			continue
+		}
+		item.To, item.origin, item.replaces = plan.Items[j].From, item.From, true
+		replaced[j] = true
+	}
+	if len(replaced) == 0 {
+		return
+	}
+	items := plan.Items[:0]
+	for i, item := range plan.Items {
+		if !replaced[i] {
+			items = append(items, item)
+		}
+	}
+	plan.Items = items
+}
+
+// Plan 遍历目标文件夹, 计算所有改名, 九宫格裁剪和 git 变动, 不修改磁盘
+func (e *Exporter) Plan(ctx context.Context) (*Plan, error) {
+	cfg := e.Cfg
+	if cfg.DirPath == "" {
+		return nil, ErrorNoDirPath
+	}
+	f, err := os.Stat(cfg.DirPath)
+	if err != nil {
+		return nil, err
+	}
+	if !f.IsDir() {
+		return nil, ErrorNotDir
+	}
+
+	e.emit(StageWalk, "", cfg.DirPath)
+	scanned := 0
+	files, err := walkDir(cfg.DirPath, "", func(file string) {
+		scanned++
+		e.progress(StageWalk, scanned, 0)
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.progress(StageWalk, len(files), len(files))
+	n, err := newNamer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// 在计算文件名前检查规则是否有效
+	v, err := newValidator(cfg)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{}
+	plan.manifest, err = loadManifest(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		item := n.planFile(file)
+		// 已经导出的文件再按规则计算会得到不同的名字 (例如模板中的 {dir}, {hash}), 保持现在的名字,
+		// 倍图等信息按原文件名计算, 九宫格已经裁剪过
+		if origin := plan.manifest.origin(file); origin != "" {
+			if exported := n.planFile(origin); exported.Error == "" {
+				exported.From, exported.To, exported.Scale = file, file, nil
+				exported.origin = origin
+				item = exported
+			}
+		}
+		plan.Items = append(plan.Items, item)
+	}
+	replaceExported(plan)
+	err = n.renderTemplate(ctx, cfg, plan.Items)
+	if err != nil {
+		return nil, err
+	}
+	// 先检查文件名, 不符合规则而不会改名的文件仍然占用原来的位置, 处理冲突时需要考虑
+	v.validate(plan)
+	v.validateRetina(cfg.DirPath, plan)
+	policy := cfg.Collision
+	if policy == "" {
+		policy = CollisionFail
+	}
+	// 按 CollisionKeep 保留的原文件名不需要检查, 加了后缀的新文件名需要重新检查
+	for _, i := range resolveConflicts(plan, policy) {
+		v.validateItem(&plan.Items[i])
+	}
+
+	err = parallel(ctx, workerCount(cfg), len(plan.Items), func(i int) {
+		item := &plan.Items[i]
+		if item.Error != "" {
+			return
+		}
+		if !e.Full && !item.Renamed() && plan.manifest.unchanged(cfg, item.From) {
+			item.Unchanged = true
+			return
+		}
+		if gitEnabled(cfg) {
+			item.Git = gitChange(cfg, *item)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
@@ -0,0 +1,187 @@
+package core
+
+import (
+	"context"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+// writePNG 在 dir 中生成一张 w x h 的图片
+func writePNG(t *testing.T, dir string, file string, w int, h int) {
+	t.Helper()
+	file = path.Join(dir, file)
+	if err := os.MkdirAll(path.Dir(file), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// pngWidth dir 中图片的宽度
+func pngWidth(t *testing.T, dir string, file string) int {
+	t.Helper()
+	f, err := os.Open(path.Join(dir, file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	c, err := png.DecodeConfig(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.Width
+}
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "chopper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// 导出后再次计算计划, 已经导出的文件不应该再改名
+func TestPlanStableAfterExport(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  ChopperCfg
+	}{
+		{"default", ChopperCfg{}},
+		{"dirs", ChopperCfg{Dirs: true, CaseStyle: CaseSnake}},
+		{"template dir", ChopperCfg{Dirs: true, Template: "{dir}_{type}_{name}"}},
+		{"template hash", ChopperCfg{Template: "{type}_{name}_{hash}"}},
+		{"template index", ChopperCfg{Dirs: true, Template: "{type}/{dir1}_{index}"}},
+		{"retina suffix", ChopperCfg{CaseStyle: CaseSnake, Retina: RetinaCfg{Suffix: "_{scale}x", Required: []int{2, 3}}}},
+		{"template retina", ChopperCfg{Template: "{name}[-{scale}x]", Retina: RetinaCfg{Required: []int{2, 3}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := tempDir(t)
+			writePNG(t, dir, "主界面/@按钮-确定@2x.png", 20, 10)
+			writePNG(t, dir, "主界面/@按钮-确定@3x.png", 30, 15)
+			writePNG(t, dir, "主界面/@背景-天空@2x.png", 8, 8)
+			writePNG(t, dir, "主界面/@背景-天空@3x.png", 12, 12)
+			writePNG(t, dir, "主界面/@图标-金币#(2,2,2,2)@2x.png", 10, 10)
+			writePNG(t, dir, "主界面/@图标-金币#(2,2,2,2)@3x.png", 15, 15)
+
+			cfg := tt.cfg
+			cfg.DirPath = dir
+			cfg.Stages = []string{StageRename}
+			e := NewExporter(cfg)
+			plan, err := e.Plan(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plan.Invalid() > 0 || plan.Unresolved() > 0 {
+				t.Fatalf("first plan has problems: %+v", plan.Items)
+			}
+			res, err := e.Apply(context.Background(), plan)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Errors) > 0 {
+				t.Fatalf("export errors: %v", res.Errors)
+			}
+
+			for run := 0; run < 2; run++ {
+				again, err := e.Plan(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, item := range again.Items {
+					if item.Error != "" {
+						t.Errorf("%s: %s", item.From, item.Error)
+					}
+					if item.Renamed() {
+						t.Errorf("%s renamed again to %s", item.From, item.To)
+					}
+				}
+				if again.Changed() > 0 {
+					t.Errorf("%d files changed after export", again.Changed())
+				}
+			}
+		})
+	}
+}
+
+// 尺寸不对的倍图在其他倍图导出改名后仍然需要检查
+func TestRetinaCheckedAfterExport(t *testing.T) {
+	dir := tempDir(t)
+	writePNG(t, dir, "@按钮-开始@2x.png", 20, 10)
+	writePNG(t, dir, "@按钮-开始@3x.png", 16, 15)
+	e := NewExporter(ChopperCfg{DirPath: dir, Stages: []string{StageRename}})
+	for run := 0; run < 2; run++ {
+		plan, err := e.Plan(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range plan.Items {
+			bad := item.From == "@按钮-开始@3x.png"
+			if bad != (item.Error != "") {
+				t.Errorf("run %d: %s error %q", run, item.From, item.Error)
+			}
+		}
+		if _, err := e.Apply(context.Background(), plan); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// 按 keep 处理冲突时保留的原文件名不是转换后的名字, 不应该因为非 ASCII 字符被拒绝
+func TestCollisionKeepOriginName(t *testing.T) {
+	dir := tempDir(t)
+	writePNG(t, dir, "@按钮-确定.png", 4, 4)
+	writePNG(t, dir, "@按钮-確定.png", 4, 4)
+	e := NewExporter(ChopperCfg{DirPath: dir, Collision: CollisionKeep, Stages: []string{StageRename}})
+	plan, err := e.Plan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Invalid() > 0 || plan.Unresolved() > 0 || len(plan.Conflicts) != 1 {
+		t.Fatalf("keep not usable: %+v %+v", plan.Items, plan.Conflicts)
+	}
+	if _, err := e.Apply(context.Background(), plan); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 新文件的序号不能使用已经导出的文件占用的序号
+func TestTemplateIndexAfterExport(t *testing.T) {
+	dir := tempDir(t)
+	writePNG(t, dir, "ui/b.png", 4, 4)
+	writePNG(t, dir, "ui/c.png", 4, 4)
+	e := NewExporter(ChopperCfg{DirPath: dir, Template: "{dir}_{index}", Stages: []string{StageRename}})
+	if _, err := e.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"ui/ui_1.png", "ui/ui_2.png"} {
+		if _, err := os.Stat(path.Join(dir, file)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writePNG(t, dir, "ui/a.png", 4, 4)
+	writePNG(t, dir, "ui/d.png", 4, 4)
+	plan, err := e.Plan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := map[string]string{}
+	for _, item := range plan.Items {
+		to[item.From] = item.To
+	}
+	if len(plan.Conflicts) > 0 || to["ui/a.png"] != "ui/ui_3.png" || to["ui/d.png"] != "ui/ui_4.png" {
+		t.Errorf("to = %v, conflicts = %+v", to, plan.Conflicts)
+	}
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// workerCount 配置的并发数, 没有配置时使用 CPU 核数
+func workerCount(cfg ChopperCfg) int {
+	if cfg.Workers > 0 {
+		return cfg.Workers
+	}
+	return runtime.NumCPU()
+}
+
+// parallel 最多使用 workers 个协程, 对 0 到 n-1 依次调用 fn
+// ctx 取消后不再处理剩下的部分, 返回 ctx.Err()
+func parallel(ctx context.Context, workers int, n int, fn func(i int)) error {
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fn(i)
+		}
+		return nil
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return ctx.Err()
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+const (
+	AppID                = "io.fyne.demo"
+	PreferenceChopperCfg = "chopperCfg"
+)
+
+var (
+	ErrorNoPreference = errors.New("没有找到界面保存的配置")
+	ErrorNotFoundCfg  = errors.New("没有找到对应的配置")
+)
+
+// PreferencePath 界面程序 (fyne) 保存配置的文件位置
+func PreferencePath() string {
+	homeDir, _ := os.UserHomeDir()
+	var root string
+	switch runtime.GOOS {
+	case "darwin":
+		root = filepath.Join(homeDir, "Library", "Preferences", "fyne")
+	case "windows":
+		root = filepath.Join(homeDir, "AppData", "Roaming", "fyne")
+	default:
+		root = filepath.Join(homeDir, ".config", "fyne")
+	}
+	return filepath.Join(root, AppID, "preferences.json")
+}
+
+// LoadPreferenceCfgs 读取界面程序保存在 chopperCfg 中的配置
+func LoadPreferenceCfgs() ([]ChopperCfg, error) {
+	data, err := ioutil.ReadFile(PreferencePath())
+	if os.IsNotExist(err) {
+		return nil, ErrorNoPreference
+	} else if err != nil {
+		return nil, err
+	}
+	var values map[string]interface{}
+	err = json.Unmarshal(data, &values)
+	if err != nil {
+		return nil, err
+	}
+	cfgStr, ok := values[PreferenceChopperCfg].(string)
+	if !ok || len(cfgStr) == 0 {
+		return nil, ErrorNoPreference
+	}
+	var cfgs []ChopperCfg
+	err = json.Unmarshal([]byte(cfgStr), &cfgs)
+	if err != nil {
+		return nil, err
+	}
+	return cfgs, nil
+}
+
+// LoadCfgFile 读取 json 配置文件, 内容可以是单个配置或配置数组
+func LoadCfgFile(file string) ([]ChopperCfg, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var cfg ChopperCfg
+		err = json.Unmarshal(data, &cfg)
+		if err != nil {
+			return nil, err
+		}
+		return []ChopperCfg{cfg}, nil
+	}
+	var cfgs []ChopperCfg
+	err = json.Unmarshal(data, &cfgs)
+	if err != nil {
+		return nil, err
+	}
+	return cfgs, nil
+}
+
+// FindCfg 按 ID 查找配置
+func FindCfg(cfgs []ChopperCfg, id int64) (*ChopperCfg, error) {
+	for i := range cfgs {
+		if cfgs[i].ID == id {
+			return &cfgs[i], nil
+		}
+	}
+	return nil, ErrorNotFoundCfg
+}
@@ -0,0 +1,21 @@
+package core
+
+// lineWriter 把 git 远端的进度输出按行 (\r 或 \n) 拆分后交给 emit
+type lineWriter struct {
+	emit func(line string)
+	buf  []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if b != '\r' && b != '\n' {
+			w.buf = append(w.buf, b)
+			continue
+		}
+		if len(w.buf) > 0 {
+			w.emit(string(w.buf))
+			w.buf = w.buf[:0]
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,229 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/disintegration/imaging"
+)
+
+// StageReport 导出结束后生成报告, 不是可以配置的阶段
+const StageReport = "report"
+
+// 报告中缩略图的最大边长
+const thumbnailSize = 96
+
+// ReportFile 报告中一个处理过的文件, 路径相对于 ChopperCfg.DirPath
+type ReportFile struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// 九宫格裁剪: left, top, right, bottom
+	Scale []int `json:"scale,omitempty"`
+	// 导出前后的文件大小, 单位字节
+	SizeBefore int64  `json:"size_before"`
+	SizeAfter  int64  `json:"size_after"`
+	Uploaded   bool   `json:"uploaded,omitempty"`
+	Error      string `json:"error,omitempty"`
+
+	// png 格式缩略图的 base64, 只用于 html 报告
+	Thumbnail string `json:"-"`
+}
+
+// Report 一次导出的报告, 保存在 DirPath/.chopper/reports/<ID>.html 和 <ID>.json
+type Report struct {
+	ID       string       `json:"id"`
+	Time     time.Time    `json:"time"`
+	Cfg      int64        `json:"cfg"`
+	Dir      string       `json:"dir"`
+	User     string       `json:"user,omitempty"`
+	Commit   string       `json:"commit,omitempty"`
+	Notified bool         `json:"notified,omitempty"`
+	Files    []ReportFile `json:"files"`
+	// 和上次导出相比没有变化的文件数量
+	Unchanged int          `json:"unchanged"`
+	Errors    []*FileError `json:"errors,omitempty"`
+	// 导出中断的原因
+	Error string `json:"error,omitempty"`
+}
+
+func reportRoot(cfg ChopperCfg) string {
+	return path.Join(cfg.DirPath, ".chopper", "reports")
+}
+
+func isImage(file string) bool {
+	return strings.HasSuffix(file, ".png") || strings.HasSuffix(file, ".jpg")
+}
+
+func thumbnail(file string) (string, error) {
+	src, err := imaging.Open(file)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	err = png.Encode(&buf, imaging.Fit(src, thumbnailSize, thumbnailSize, imaging.Linear))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// newReport 汇总导出结果, 在 job.finish 之后调用
+func (job *Job) newReport(err error) *Report {
+	res := job.Result
+	report := &Report{
+		ID:        res.Journal,
+		Time:      time.Now(),
+		Cfg:       job.Cfg.ID,
+		Dir:       job.Cfg.DirPath,
+		User:      historyUser(job.Cfg),
+		Commit:    res.Commit,
+		Notified:  res.Notified,
+		Unchanged: res.Unchanged,
+		Errors:    res.Errors,
+	}
+	if err != nil {
+		report.Error = err.Error()
+	}
+	fileErrors := map[string]string{}
+	for _, e := range res.Errors {
+		if e.File != "" && fileErrors[e.File] == "" {
+			fileErrors[e.File] = e.Error()
+		}
+	}
+	for i, item := range job.Plan.Items {
+		if item.Unchanged {
+			continue
+		}
+		file := ReportFile{
+			From:       item.From,
+			To:         job.Files[i],
+			Scale:      item.Scale,
+			SizeBefore: job.sizes[i],
+			Error:      fileErrors[item.From],
+		}
+		if info, err := os.Stat(path.Join(job.Cfg.DirPath, job.Files[i])); err == nil {
+			file.SizeAfter = info.Size()
+		}
+		_, file.Uploaded = res.Uploaded[job.Files[i]]
+		report.Files = append(report.Files, file)
+	}
+	return report
+}
+
+// writeReport 生成 html 和 json 报告, 返回 html 报告的路径
+// ctx 取消后不再生成缩略图, 报告仍然会写入
+func (job *Job) writeReport(ctx context.Context, err error) (string, error) {
+	report := job.newReport(err)
+	_ = parallel(ctx, job.Workers(), len(report.Files), func(i int) {
+		file := &report.Files[i]
+		if !isImage(file.To) {
+			return
+		}
+		// 缩略图只是预览, 生成失败时不显示即可
+		file.Thumbnail, _ = thumbnail(path.Join(job.Cfg.DirPath, file.To))
+	})
+
+	dir := reportRoot(job.Cfg)
+	e := os.MkdirAll(dir, os.ModePerm)
+	if e != nil {
+		return "", e
+	}
+	data, e := json.MarshalIndent(report, "", "  ")
+	if e != nil {
+		return "", e
+	}
+	e = ioutil.WriteFile(path.Join(dir, report.ID+".json"), data, 0644)
+	if e != nil {
+		return "", e
+	}
+	var buf bytes.Buffer
+	e = reportTemplate.Execute(&buf, report)
+	if e != nil {
+		return "", e
+	}
+	file := path.Join(dir, report.ID+".html")
+	return file, ioutil.WriteFile(file, buf.Bytes(), 0644)
+}
+
+func formatSize(size int64) string {
+	switch {
+	case size < 1024:
+		return fmt.Sprintf("%d B", size)
+	case size < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%.1f MB", float64(size)/1024/1024)
+	}
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"size": formatSize,
+	"thumb": func(data string) template.URL {
+		return template.URL("data:image/png;base64," + data)
+	},
+	"saved": func(f ReportFile) string {
+		if f.SizeBefore == 0 || f.SizeAfter >= f.SizeBefore {
+			return ""
+		}
+		return fmt.Sprintf("-%.0f%%", float64(f.SizeBefore-f.SizeAfter)*100/float64(f.SizeBefore))
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>导出报告 {{.Time.Format "2006-01-02 15:04:05"}}</title>
+<style>
+body { font-family: -apple-system, "PingFang SC", "Microsoft YaHei", sans-serif; margin: 24px; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: 6px 8px; text-align: left; vertical-align: middle; }
+th { background: #f5f5f5; }
+td.num { text-align: right; white-space: nowrap; }
+img { max-width: 96px; max-height: 96px; background: repeating-conic-gradient(#eee 0% 25%, #fff 0% 50%) 50% / 16px 16px; }
+.error { color: #c00; }
+</style>
+</head>
+<body>
+<h1>导出报告</h1>
+<p>
+时间: {{.Time.Format "2006-01-02 15:04:05"}}<br>
+目录: {{.Dir}}<br>
+{{if .User}}执行人: {{.User}}<br>{{end}}
+{{if .Commit}}git 提交: <code>{{.Commit}}</code><br>{{end}}
+{{if .Notified}}已发送机器人通知<br>{{end}}
+处理 {{len .Files}} 个文件, 未变化 {{.Unchanged}} 个, 错误 {{len .Errors}} 个
+</p>
+{{if .Error}}<p class="error">导出中断: {{.Error}}</p>{{end}}
+<table>
+<tr><th>预览</th><th>原文件</th><th>新文件</th><th>九宫格</th><th>导出前</th><th>导出后</th><th></th><th>上传</th></tr>
+{{range .Files}}
+<tr>
+<td>{{if .Thumbnail}}<img src="{{thumb .Thumbnail}}">{{end}}</td>
+<td>{{.From}}</td>
+<td>{{if ne .From .To}}{{.To}}{{else}}-{{end}}</td>
+<td>{{if .Scale}}{{index .Scale 0}}, {{index .Scale 1}}, {{index .Scale 2}}, {{index .Scale 3}}{{else}}-{{end}}</td>
+<td class="num">{{size .SizeBefore}}</td>
+<td class="num">{{size .SizeAfter}}</td>
+<td class="num">{{saved .}}</td>
+<td>{{if .Uploaded}}✓{{end}}{{if .Error}}<span class="error">{{.Error}}</span>{{end}}</td>
+</tr>
+{{end}}
+</table>
+{{if .Errors}}
+<h2>错误</h2>
+<ul class="error">
+{{range .Errors}}<li>{{.Error}}</li>{{end}}
+</ul>
+{{end}}
+</body>
+</html>
+`))
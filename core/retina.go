@@ -0,0 +1,173 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultRetinaSuffix 导出后倍图后缀的默认形式, 和 sketch 一致
+const DefaultRetinaSuffix = "@{scale}x"
+
+var (
+	ErrorRetinaSuffix = errors.New("倍图后缀需要包含 {scale}")
+
+	// sketch 导出的倍图, 例如 开始@2x.png
+	regRetina = regexp.MustCompile(`@(\d+)x$`)
+)
+
+// RetinaCfg 倍图配置
+type RetinaCfg struct {
+	// 导出后的倍图后缀, 例如 _{scale}x, 默认 DefaultRetinaSuffix
+	Suffix string `json:"suffix,omitempty"`
+	// 每张图片都需要有的倍数, 没有倍数后缀的图片为 1 倍, 例如 [2, 3]
+	Required []int `json:"required,omitempty"`
+}
+
+// retinaRegexp 匹配按配置的形式生成的倍数后缀, 例如 _{scale}x 匹配 _2x
+func retinaRegexp(suffix string) *regexp.Regexp {
+	quoted := strings.Replace(regexp.QuoteMeta(suffix), regexp.QuoteMeta("{scale}"), `(\d+)`, 1)
+	return regexp.MustCompile(quoted + "$")
+}
+
+// parseRetina 拆出名字 (不含扩展名) 末尾 sketch 形式或 suffix 形式的倍数后缀, 没有时倍数为 0
+func parseRetina(name string, suffix *regexp.Regexp) (string, int) {
+	for _, reg := range []*regexp.Regexp{regRetina, suffix} {
+		if reg == nil {
+			continue
+		}
+		loc := reg.FindStringSubmatchIndex(name)
+		if len(loc) == 0 {
+			continue
+		}
+		scale, err := strconv.Atoi(name[loc[2]:loc[3]])
+		if err != nil || scale == 0 {
+			continue
+		}
+		return name[:loc[0]], scale
+	}
+	return name, 0
+}
+
+func newRetinaSuffix(cfg RetinaCfg) (string, error) {
+	if cfg.Suffix == "" {
+		return DefaultRetinaSuffix, nil
+	}
+	if !strings.Contains(cfg.Suffix, "{scale}") {
+		return "", ErrorRetinaSuffix
+	}
+	return cfg.Suffix, nil
+}
+
+// retinaSuffix 按配置的形式生成倍数后缀
+func (n *namer) retinaSuffix(scale int) string {
+	if scale == 0 {
+		return ""
+	}
+	return strings.Replace(n.retina, "{scale}", strconv.Itoa(scale), -1)
+}
+
+// retinaVariant 同一张图片的一个倍图
+type retinaVariant struct {
+	index  int
+	scale  int
+	width  int
+	height int
+}
+
+// validateRetina 检查每张图片是否有需要的所有倍图, 以及各倍图的尺寸是否和倍数成比例
+func (v *validator) validateRetina(dir string, plan *Plan) {
+	groups := map[string][]*retinaVariant{}
+	var keys []string
+	for i := range plan.Items {
+		item := &plan.Items[i]
+		if item.variant == "" {
+			continue
+		}
+		key := nameKey(item.variant)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		scale := item.Retina
+		if scale == 0 {
+			scale = 1
+		}
+		groups[key] = append(groups[key], &retinaVariant{index: i, scale: scale})
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		variants := groups[key]
+		// 只有一个倍数又不要求倍图时不需要检查
+		if len(variants) < 2 && len(v.retina) == 0 {
+			continue
+		}
+		problems := map[int][]string{}
+		have := map[int]bool{}
+		for _, variant := range variants {
+			have[variant.scale] = true
+		}
+		var missing []string
+		for _, scale := range v.retina {
+			if !have[scale] {
+				missing = append(missing, "@"+strconv.Itoa(scale)+"x")
+			}
+		}
+		if len(missing) > 0 {
+			for _, variant := range variants {
+				problems[variant.index] = append(problems[variant.index], "缺少倍图 "+strings.Join(missing, ", "))
+			}
+		}
+
+		// 以倍数最小的图片为准检查其他倍图的尺寸
+		var sized []*retinaVariant
+		for _, variant := range variants {
+			if plan.Items[variant.index].Error != "" {
+				continue
+			}
+			f, err := os.Open(path.Join(dir, plan.Items[variant.index].From))
+			if err != nil {
+				continue
+			}
+			config, _, err := image.DecodeConfig(f)
+			f.Close()
+			if err != nil {
+				continue
+			}
+			variant.width, variant.height = config.Width, config.Height
+			sized = append(sized, variant)
+		}
+		sort.SliceStable(sized, func(a, b int) bool {
+			return sized[a].scale < sized[b].scale
+		})
+		for i := 1; i < len(sized); i++ {
+			base, variant := sized[0], sized[i]
+			if !retinaMatch(base.width, base.scale, variant.width, variant.scale) ||
+				!retinaMatch(base.height, base.scale, variant.height, variant.scale) {
+				problems[variant.index] = append(problems[variant.index], fmt.Sprintf("@%dx 的尺寸 %dx%d 和 @%dx 的 %dx%d 不成比例, 应为 %dx%d",
+					variant.scale, variant.width, variant.height, base.scale, base.width, base.height,
+					base.width*variant.scale/base.scale, base.height*variant.scale/base.scale))
+			}
+		}
+
+		for index, p := range problems {
+			v.report(&plan.Items[index], p)
+		}
+	}
+}
+
+// retinaMatch 允许按倍数换算时取整造成的误差
+func retinaMatch(base int, baseScale int, size int, scale int) bool {
+	diff := size*baseScale - base*scale
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff < baseScale
+}
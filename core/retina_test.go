@@ -0,0 +1,37 @@
+package core
+
+import "testing"
+
+func TestPlanFileRetina(t *testing.T) {
+	testPlanFile(t, []planFileCase{
+		{"retina", ChopperCfg{}, "主界面/@按钮-确定@2x.png", "主界面/btn_que4ding4@2x.png", nil, 2, false},
+		{"retina suffix", ChopperCfg{CaseStyle: CaseSnake, Retina: RetinaCfg{Suffix: "_{scale}x"}}, "@按钮-确定@2x.png", "btn_que4ding4_2x.png", nil, 2, false},
+		{"retina 9scale", ChopperCfg{}, "@图标-金币#(2)@3x.jpg", "icon_jin1bi4@3x.jpg", []int{2, 2, 2, 2}, 3, false},
+	})
+}
+
+func TestParseRetina(t *testing.T) {
+	tests := []struct {
+		name   string
+		suffix string
+		base   string
+		scale  int
+	}{
+		{"开始@2x", DefaultRetinaSuffix, "开始", 2},
+		{"开始@3x", "_{scale}x", "开始", 3},
+		{"start_2x", "_{scale}x", "start", 2},
+		{"start-2x", "_{scale}x", "start-2x", 0},
+		{"start@2x", "-{scale}", "start", 2},
+		{"start-2", "-{scale}", "start", 2},
+		{"开始", DefaultRetinaSuffix, "开始", 0},
+		{"开始@0x", DefaultRetinaSuffix, "开始@0x", 0},
+		{"a.b_2x", ".b_{scale}x", "a", 2},
+		{"axb_2x", ".b_{scale}x", "axb_2x", 0},
+	}
+	for _, tt := range tests {
+		base, scale := parseRetina(tt.name, retinaRegexp(tt.suffix))
+		if base != tt.base || scale != tt.scale {
+			t.Errorf("parseRetina(%s, %s) = %s, %d, want %s, %d", tt.name, tt.suffix, base, scale, tt.base, tt.scale)
+		}
+	}
+}
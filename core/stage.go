@@ -0,0 +1,518 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	ErrorUnknownStage = errors.New("未知的导出阶段")
+	ErrorTargetExists = errors.New("目标文件已存在")
+)
+
+// Stage 导出流程中的一个阶段, 通过 RegisterStage 注册后即可在 ChopperCfg.Stages 中使用
+type Stage interface {
+	Name() string
+	// ctx 取消后应尽快在文件之间停止并返回 ctx.Err()
+	Run(ctx context.Context, job *Job) error
+}
+
+// Job 一次导出中各阶段共享的状态
+type Job struct {
+	Cfg    ChopperCfg
+	Plan   *Plan
+	Result *Result
+	// 计划中每个文件当前的位置, 与 Plan.Items 一一对应
+	Files []string
+
+	stages   []Stage
+	exporter *Exporter
+	journal  *Journal
+	manifest *Manifest
+	// 导出前每个文件的大小, 用于报告
+	sizes  []int64
+	failed map[int]bool
+	mu     sync.Mutex
+}
+
+// Workers 同时处理文件的协程数
+func (job *Job) Workers() int {
+	return workerCount(job.Cfg)
+}
+
+// Fail 记录第 i 个文件的错误, 之后的阶段不再处理这个文件, 可以并发调用
+func (job *Job) Fail(i int, stage string, err error) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	file := ""
+	if i >= 0 {
+		file = job.Plan.Items[i].From
+		if job.failed == nil {
+			job.failed = map[int]bool{}
+		}
+		job.failed[i] = true
+	}
+	job.Result.Errors = append(job.Result.Errors, &FileError{
+		File:  file,
+		Stage: stage,
+		Err:   err,
+	})
+	job.Emit(stage, file, err.Error())
+}
+
+// Failed 第 i 个文件是否已经出错
+func (job *Job) Failed(i int) bool {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	return job.failed[i]
+}
+
+// Processing 第 i 个文件是否需要处理: 和上次导出相比有变化, 并且还没有出错
+func (job *Job) Processing(i int) bool {
+	return !job.Plan.Items[i].Unchanged && !job.Failed(i)
+}
+
+// Active 需要处理的文件的当前位置
+func (job *Job) Active() []string {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	var files []string
+	for i, file := range job.Files {
+		if !job.Plan.Items[i].Unchanged && !job.failed[i] {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+func (job *Job) finish() {
+	job.Result.Files = job.Active()
+	for _, item := range job.Plan.Items {
+		if item.Unchanged {
+			job.Result.Unchanged++
+		}
+	}
+	for i, item := range job.Plan.Items {
+		if job.failed[i] {
+			job.Result.Skipped = append(job.Result.Skipped, item.From)
+		}
+	}
+	for i, item := range job.Plan.Items {
+		if item.Unchanged || job.failed[i] {
+			continue
+		}
+		for _, phrase := range item.Overrides {
+			if job.Result.Overrides == nil {
+				job.Result.Overrides = map[string]int{}
+			}
+			job.Result.Overrides[phrase]++
+		}
+	}
+	for _, term := range job.Plan.Untranslated() {
+		job.Result.Untranslated = append(job.Result.Untranslated, term.Term)
+	}
+}
+
+// HasStage 本次导出是否启用了指定阶段
+func (job *Job) HasStage(name string) bool {
+	for _, stage := range job.stages {
+		if stage.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Emit 发出进度事件
+func (job *Job) Emit(stage string, file string, message string) {
+	job.exporter.emit(stage, file, message)
+}
+
+// Progress 发出阶段进度, total 为 0 表示总数未知
+func (job *Job) Progress(stage string, done int, total int) {
+	job.exporter.progress(stage, done, total)
+}
+
+// Backup 覆盖文件前调用, 记录原内容以便撤销
+func (job *Job) Backup(file string) error {
+	return job.journal.backup(file)
+}
+
+// Move 移动文件并记录到修改日志, 目标位置已经有其他文件时不移动, 避免覆盖后无法撤销
+func (job *Job) Move(from string, to string) error {
+	src, dst := path.Join(job.Cfg.DirPath, from), path.Join(job.Cfg.DirPath, to)
+	// 不区分大小写的文件系统上只改大小写时, 目标就是文件本身
+	if target, err := os.Lstat(dst); err == nil {
+		if info, err := os.Lstat(src); err != nil || !os.SameFile(info, target) {
+			return fmt.Errorf("%w: %s", ErrorTargetExists, to)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	return job.move(from, to)
+}
+
+// Replace 用 from 替换之前导出的 to, 先备份 to 以便撤销
+func (job *Job) Replace(from string, to string) error {
+	if _, err := os.Lstat(path.Join(job.Cfg.DirPath, to)); err == nil {
+		err = job.Backup(to)
+		if err != nil {
+			return err
+		}
+	}
+	return job.move(from, to)
+}
+
+func (job *Job) move(from string, to string) error {
+	// 文件夹名转换后目标文件夹可能还不存在
+	err := os.MkdirAll(path.Dir(path.Join(job.Cfg.DirPath, to)), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(path.Join(job.Cfg.DirPath, from), path.Join(job.Cfg.DirPath, to))
+	if err != nil {
+		return err
+	}
+	return job.journal.renamed(from, to)
+}
+
+// Images 当前所有需要处理的 png, jpg 图片
+func (job *Job) Images() []string {
+	var images []string
+	for _, file := range job.Active() {
+		ext := path.Ext(file)
+		if ext != ".png" && ext != ".jpg" {
+			continue
+		}
+		images = append(images, file)
+	}
+	return images
+}
+
+var stages = map[string]Stage{}
+
+// DefaultStages 没有配置 ChopperCfg.Stages 时使用的阶段和顺序
+var DefaultStages = []string{Stage9Scale, StageRename, StageCompress, StageUpload, StageRobot}
+
+func RegisterStage(stage Stage) {
+	stages[stage.Name()] = stage
+}
+
+func init() {
+	RegisterStage(scaleStage{})
+	RegisterStage(renameStage{})
+	RegisterStage(compressStage{})
+	RegisterStage(uploadStage{})
+	RegisterStage(robotStage{})
+}
+
+// CfgStages 按配置顺序返回启用的阶段
+func CfgStages(cfg ChopperCfg) ([]Stage, error) {
+	names := cfg.Stages
+	if names == nil {
+		names = DefaultStages
+	}
+	var list []Stage
+	for _, name := range names {
+		stage, ok := stages[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrorUnknownStage, name)
+		}
+		list = append(list, stage)
+	}
+	return list, nil
+}
+
+// StageNames 所有已注册的阶段, 默认阶段在前
+func StageNames() []string {
+	var extra []string
+	for name := range stages {
+		found := false
+		for _, n := range DefaultStages {
+			if n == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	return append(append([]string(nil), DefaultStages...), extra...)
+}
+
+type scaleStage struct{}
+
+func (scaleStage) Name() string {
+	return Stage9Scale
+}
+
+func (scaleStage) Run(ctx context.Context, job *Job) error {
+	var scales []int
+	for i, item := range job.Plan.Items {
+		if item.Scale != nil && job.Processing(i) {
+			scales = append(scales, i)
+		}
+	}
+	errs := make([]error, len(scales))
+	done := make([]bool, len(scales))
+	var count int32
+	job.Progress(Stage9Scale, 0, len(scales))
+	err := parallel(ctx, job.Workers(), len(scales), func(n int) {
+		i := scales[n]
+		item := job.Plan.Items[i]
+		file := job.Files[i]
+		job.Emit(Stage9Scale, item.From, fmt.Sprint(item.Scale))
+		err := job.Backup(file)
+		if err == nil {
+			err = handle9Scale(path.Join(job.Cfg.DirPath, file), item.Scale[0], item.Scale[1], item.Scale[2], item.Scale[3])
+		}
+		errs[n] = err
+		done[n] = true
+		job.Progress(Stage9Scale, int(atomic.AddInt32(&count, 1)), len(scales))
+	})
+	// 按计划顺序汇总结果, 保证每次导出的结果一致
+	for n, i := range scales {
+		if !done[n] {
+			continue
+		}
+		if errs[n] != nil {
+			job.Fail(i, Stage9Scale, errs[n])
+			continue
+		}
+		job.Result.Scaled = append(job.Result.Scaled, job.Plan.Items[i].From)
+	}
+	return err
+}
+
+type renameStage struct{}
+
+func (renameStage) Name() string {
+	return StageRename
+}
+
+// Run 改名很快, 只在开始前检查取消, 避免留下改到一半的临时文件
+func (renameStage) Run(ctx context.Context, job *Job) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	// 文件名被其他文件的新文件名占用时, 先移到临时文件名, 避免依次改名时互相覆盖
+	targets := map[string]bool{}
+	for _, item := range job.Plan.Items {
+		if item.Renamed() {
+			targets[nameKey(item.To)] = true
+		}
+	}
+	for i, item := range job.Plan.Items {
+		if !item.Renamed() || !job.Processing(i) || !targets[nameKey(job.Files[i])] {
+			continue
+		}
+		tmp := job.Files[i] + ".chopper-tmp"
+		err := job.Move(job.Files[i], tmp)
+		if err != nil {
+			job.Fail(i, StageRename, err)
+			continue
+		}
+		job.Files[i] = tmp
+	}
+
+	total := 0
+	for i, item := range job.Plan.Items {
+		if item.Renamed() && job.Processing(i) {
+			total++
+		}
+	}
+	done := 0
+	job.Progress(StageRename, done, total)
+	for i, item := range job.Plan.Items {
+		if !item.Renamed() || !job.Processing(i) {
+			continue
+		}
+		done++
+		job.Progress(StageRename, done, total)
+		job.Emit(StageRename, item.From, item.To)
+		move := job.Move
+		if item.replaces {
+			move = job.Replace
+		}
+		err := move(job.Files[i], item.To)
+		if err != nil {
+			job.Fail(i, StageRename, err)
+			continue
+		}
+		job.Files[i] = item.To
+		job.Result.Renamed = append(job.Result.Renamed, Rename{From: item.From, To: item.To})
+	}
+	// 文件夹名转换后, 原来的文件夹中没有其他文件时删除
+	for _, r := range job.Result.Renamed {
+		if path.Dir(r.From) != path.Dir(r.To) {
+			removeEmptyDirs(job.Cfg.DirPath, path.Dir(r.From))
+		}
+	}
+	return nil
+}
+
+type compressStage struct{}
+
+func (compressStage) Name() string {
+	return StageCompress
+}
+
+func (compressStage) Run(ctx context.Context, job *Job) error {
+	_, err := findImageOptim()
+	if err != nil {
+		job.Emit(StageCompress, "", err.Error())
+		return nil
+	}
+	var indexes []int
+	for i, file := range job.Files {
+		ext := path.Ext(file)
+		if job.Processing(i) && (ext == ".png" || ext == ".jpg") {
+			indexes = append(indexes, i)
+		}
+	}
+	// 压缩会覆盖原图, 需要先备份
+	errs := make([]error, len(indexes))
+	err = parallel(ctx, job.Workers(), len(indexes), func(n int) {
+		errs[n] = job.Backup(job.Files[indexes[n]])
+	})
+	if err != nil {
+		return err
+	}
+	var allImages []string
+	for n, i := range indexes {
+		if errs[n] != nil {
+			job.Fail(i, StageCompress, errs[n])
+			continue
+		}
+		allImages = append(allImages, path.Join(job.Cfg.DirPath, job.Files[i]))
+	}
+
+	// 图片分组后交给多个压缩进程同时处理, 出错时无法对应到单个文件
+	workers := job.Workers()
+	size := (len(allImages) + workers - 1) / workers
+	var groups [][]string
+	for start := 0; start < len(allImages); start += size {
+		end := start + size
+		if end > len(allImages) {
+			end = len(allImages)
+		}
+		groups = append(groups, allImages[start:end])
+	}
+	errs = make([]error, len(groups))
+	var count int32
+	job.Progress(StageCompress, 0, len(allImages))
+	err = parallel(ctx, workers, len(groups), func(n int) {
+		errs[n] = compressImage(ctx, groups[n]...)
+		job.Progress(StageCompress, int(atomic.AddInt32(&count, int32(len(groups[n])))), len(allImages))
+	})
+	if err != nil {
+		return err
+	}
+	for _, err := range errs {
+		if err != nil {
+			job.Fail(-1, StageCompress, err)
+		}
+	}
+	return nil
+}
+
+type uploadStage struct{}
+
+func (uploadStage) Name() string {
+	return StageUpload
+}
+
+func (uploadStage) Run(ctx context.Context, job *Job) error {
+	if !gitEnabled(job.Cfg) {
+		job.Emit(StageUpload, "", "没有配置 git, 跳过上传")
+		return nil
+	}
+	job.Emit(StageUpload, "", job.Cfg.Git.URL)
+	files := job.Active()
+	// 没有变化但还不在 .remote 中的文件 (例如之前没有开启上传) 也需要上传
+	for i, item := range job.Plan.Items {
+		if !item.Unchanged {
+			continue
+		}
+		_, err := os.Stat(path.Join(job.Cfg.DirPath, ".remote", job.Files[i]))
+		if os.IsNotExist(err) {
+			files = append(files, job.Files[i])
+		}
+	}
+	// 原文件名记录到文件名对照表, 没有改名的文件使用上次导出时记录的原文件名
+	origins := map[string]string{}
+	for i, item := range job.Plan.Items {
+		origins[job.Files[i]] = item.From
+		if job.manifest == nil || item.Renamed() {
+			continue
+		}
+		if entry, ok := job.manifest.Files[job.Files[i]]; ok && entry.Origin != "" {
+			origins[job.Files[i]] = entry.Origin
+		}
+	}
+	var removed []string
+	for _, r := range job.Result.Renamed {
+		removed = append(removed, r.From)
+	}
+	copied := 0
+	job.Progress(StageUpload, 0, len(files))
+	uploaded, commit, err := gitUpload(ctx, job.Cfg, files, origins, removed, uploadProgress{
+		copied: func(file string, err error) {
+			copied++
+			job.Progress(StageUpload, copied, len(files))
+			if err == nil {
+				return
+			}
+			for i := range job.Files {
+				if job.Files[i] == file {
+					job.Fail(i, StageUpload, err)
+				}
+			}
+		},
+		remote: &lineWriter{
+			emit: func(line string) {
+				job.Emit(StageUpload, "", line)
+			},
+		},
+	})
+	job.Result.Uploaded = uploaded
+	job.Result.Commit = commit
+	return err
+}
+
+type robotStage struct{}
+
+func (robotStage) Name() string {
+	return StageRobot
+}
+
+func (robotStage) Run(ctx context.Context, job *Job) error {
+	// 开启上传时只在有文件更新后通知
+	if job.HasStage(StageUpload) && len(job.Result.Uploaded) == 0 {
+		return nil
+	}
+	if job.Cfg.Robot.Name == "" || job.Cfg.Robot.Content == "" {
+		job.Emit(StageRobot, "", "没有配置机器人, 跳过通知")
+		return nil
+	}
+	job.Emit(StageRobot, "", job.Cfg.Robot.Name)
+	job.Progress(StageRobot, 0, 1)
+	sent, err := robot(ctx, job.Cfg)
+	if err != nil {
+		return err
+	}
+	if !sent {
+		job.Emit(StageRobot, "", "未知的机器人, 跳过通知: "+job.Cfg.Robot.Name)
+	}
+	job.Progress(StageRobot, 1, 1)
+	job.Result.Notified = sent
+	return nil
+}
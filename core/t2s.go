@@ -0,0 +1,76 @@
+package core
+
+import "strings"
+
+// t2sTable 常用繁体字和对应的简体字, 每两个字为一组
+// 只收录一一对应的字, 乾, 著, 瞭 等本身也是规范简体字的不转换
+const t2sTable = "萬万與与醜丑專专業业叢丛東东絲丝兩两嚴严喪丧個个豐丰臨临為为麗丽舉举義义烏乌樂乐喬乔習习鄉乡書书買买亂乱爭争於于虧亏雲云" +
+	"亞亚產产畝亩親亲億亿僅仅從从侖仑倉仓儀仪們们價价眾众優优會会傘伞偉伟傳传傷伤倫伦偽伪體体餘余傭佣僉佥俠侠侶侣僥侥偵侦側侧" +
+	"僑侨儈侩儕侪儂侬俁俣儔俦儼俨倆俩儷俪儉俭債债傾倾僂偻僨偾償偿儲储兒儿兌兑黨党蘭兰關关興兴養养獸兽內内岡冈冊册寫写軍军農农" +
+	"馮冯衝冲決决況况凍冻淨净涼凉減减湊凑凜凛幾几鳳凤憑凭凱凯擊击鑿凿芻刍劃划劉刘則则剛刚創创刪删別别剎刹劑剂剮剐劍剑剝剥劇剧" +
+	"勸劝辦办務务動动勵励勁劲勞劳勢势勳勋勻匀匯汇匭匦區区醫医華华協协單单賣卖盧卢衛卫卻却廠厂廳厅曆历歷历厲厉壓压厭厌廁厕廂厢" +
+	"廈厦廚厨廝厮縣县參参雙双發发變变敘叙疊叠葉叶號号嘆叹嘰叽嚇吓呂吕嗎吗噸吨聽听啟启吳吴吶呐嘸呒囈呓嘔呕嚦呖唄呗員员咼呙嗆呛" +
+	"嗚呜詠咏嚨咙嚀咛響响啞哑噠哒嘵哓嗶哔噦哕嘩哗喲哟嘮唠嘯啸喚唤嗩唢囀啭嘖啧嗇啬囑嘱嚕噜囂嚣團团園园圍围圖图圓圆聖圣場场壞坏" +
+	"塊块堅坚壇坛壩坝塢坞墳坟墜坠壟垄壚垆壘垒墾垦堊垩埡垭塏垲塒埘塤埙堝埚墊垫塹堑墮堕壯壮聲声殼壳壺壶處处備备復复複复夠够頭头" +
+	"誇夸夾夹奪夺奮奋獎奖奧奥妝妆婦妇媽妈嫵妩嫗妪姍姗婁娄婭娅嬌娇孌娈娛娱媧娲嫻娴嬰婴嬸婶孫孙學学孿孪寧宁寶宝實实寵宠審审憲宪" +
+	"宮宫寬宽賓宾寢寝對对尋寻導导壽寿將将爾尔塵尘嘗尝堯尧盡尽層层屆届屍尸屬属屢屡嶼屿歲岁豈岂嶇岖崗岗峴岘嵐岚島岛嶺岭嶽岳崠岽" +
+	"巋岿嶧峄峽峡嶠峤崢峥巒峦嶗崂崍崃嶄崭嶸嵘鞏巩幣币帥帅師师幃帏帳帐簾帘幟帜帶带幀帧幫帮幬帱幗帼幹干並并廣广莊庄慶庆廬庐廡庑" +
+	"庫库應应廟庙龐庞廢废開开異异棄弃張张彌弥彎弯彈弹強强歸归當当錄录彥彦徹彻徑径徠徕憶忆懺忏憂忧懷怀態态慫怂憐怜總总懟怼戀恋" +
+	"懇恳惡恶慟恸懨恹愷恺惻恻惱恼惲恽悅悦懸悬慳悭驚惊懼惧慘惨懲惩憊惫愜惬慚惭憚惮慣惯慍愠憤愤憫悯願愿懾慑戰战戲戏戶户拋抛撲扑" +
+	"執执擴扩捫扪掃扫揚扬擾扰撫抚搶抢護护報报擬拟攏拢揀拣擁拥攔拦擰拧撥拨擇择掛挂摯挚攣挛撾挝撻挞挾挟撓挠擋挡撟挢掙挣擠挤揮挥" +
+	"撈捞損损撿捡換换搗捣據据擄掳摑掴擲掷撣掸摻掺摜掼攬揽攙搀擱搁摟搂攪搅攜携攝摄攄摅擺摆搖摇擯摈攤摊攖撄撐撑攆撵擷撷擼撸攛撺" +
+	"擻擞敵敌斂敛數数齋斋斕斓鬥斗斬斩斷断無无舊旧時时曠旷暘旸曇昙晝昼顯显晉晋曬晒曉晓曄晔暈晕暉晖暫暂曖暧術术樸朴機机殺杀雜杂" +
+	"權权條条來来楊杨榪杩傑杰極极構构樅枞樞枢棗枣櫪枥梘枧棖枨槍枪楓枫梟枭櫃柜檸柠檉柽梔栀柵栅標标棧栈櫛栉櫳栊棟栋櫨栌櫟栎欄栏" +
+	"樹树棲栖樣样欒栾棬桊椏桠橈桡楨桢檔档榿桤橋桥樺桦檜桧槳桨樁桩夢梦檢检欞棂槨椁櫝椟槧椠槓杠櫚榈櫸榉樓楼欖榄櫬榇榮荣槤梿檯台" +
+	"檣樯櫻樱櫥橱橫横櫞橼歡欢歐欧殲歼殤殇殘残殞殒殮殓殫殚殯殡毆殴毀毁轂毂畢毕斃毙氈毡氌氇氣气氫氢氬氩氳氲漢汉湯汤溝沟沒没灃沣" +
+	"漚沤瀝沥淪沦滄沧溈沩滬沪濘泞淚泪澩泶瀧泷瀘泸濼泺潑泼澤泽涇泾潔洁灑洒窪洼浹浃淺浅漿浆澆浇湞浈濁浊測测澮浍濟济瀏浏滸浒渾浑" +
+	"滻浐濃浓潯浔濤涛澇涝淶涞漣涟潿涠渦涡渙涣滌涤潤润澗涧漲涨澀涩淵渊漬渍瀆渎漸渐澠渑漁渔瀋沈滲渗溫温灣湾濕湿潰溃濺溅漵溆滎荥" +
+	"滿满瀅滢濾滤濫滥灤滦濱滨灘滩澦滪瀠潆瀟潇瀲潋濰潍潛潜瀨濑瀰弥滅灭燈灯靈灵災灾燦灿煬炀爐炉燉炖煒炜熗炝點点煉炼熾炽爍烁爛烂" +
+	"烴烃燭烛煙烟煩烦燒烧燁烨燴烩燙烫燼烬熱热煥焕燜焖燾焘愛爱爺爷牘牍犛牦牽牵犧牺犢犊狀状獷犷猶犹狹狭獅狮獨独狽狈獰狞獄狱貓猫" +
+	"獵猎獻献獼猕玀猡現现瑪玛環环璽玺瑋玮瑩莹瑣琐瓊琼璉琏瑤瑶璣玑璦瑷瓏珑甌瓯甕瓮電电畫画暢畅疇畴療疗瘧疟癘疠瘍疡癤疖瘡疮瘋疯" +
+	"皰疱痙痉癰痈痺痹瘂痖瘓痪癆痨瘞瘗瘻瘘癱瘫癮瘾癭瘿癩癞癬癣癲癫皚皑皺皱盞盏鹽盐監监蓋盖盜盗盤盘瞘眍眥眦矚瞩睜睁瞼睑瞞瞒礦矿" +
+	"碼码磚砖礪砺確确礎础礙碍磯矶硯砚碩硕礬矾礫砾碭砀碸砜礱砻礡礴禮礼禱祷禍祸禪禅離离禿秃種种積积稱称穢秽稅税穩稳穌稣窩窝窮穷" +
+	"竊窃竅窍窯窑竄窜竇窦豎竖競竞筆笔筍笋籠笼箋笺築筑篤笃篩筛節节範范簡简籌筹簽签簫箫籃篮籬篱糧粮糾纠紀纪紂纣約约紅红紆纡紇纥" +
+	"紈纨紉纫緯纬紜纭紘纮純纯紕纰紗纱綱纲納纳縱纵綸纶紛纷紙纸紋纹紡纺紐纽紓纾線线紺绀絏绁紱绂練练組组紳绅細细織织終终縐绉絆绊" +
+	"紼绋絀绌紹绍繹绎經经紿绐綁绑絨绒結结絝绔繞绕絎绗給给絢绚絳绛絡络絕绝絞绞統统綆绠綃绡絹绢綏绥繼继綈绨績绩緒绪綾绫續续綺绮" +
+	"緋绯綽绰緄绲繩绳維维綿绵綬绶綢绸綹绺綻绽綜综綴缀緇缁緙缂緗缃緘缄緬缅纜缆緹缇緲缈緝缉縕缊緞缎締缔緣缘編编緩缓緡缗緱缑縋缒" +
+	"緶缏縛缚縟缛縉缙縫缝纏缠縞缟縭缡縊缢縑缣繽缤縹缥縵缦縲缧纓缨縮缩繆缪繅缫纈缬繚缭繕缮繒缯繳缴纘缵罌罂網网羅罗罰罚罷罢羆罴" +
+	"羈羁羥羟翹翘耬耧聳耸恥耻聶聂聾聋職职聹聍聯联聰聪肅肃腸肠膚肤腎肾腫肿脹胀脅胁膽胆勝胜朧胧臚胪脛胫膠胶脈脉膾脍臍脐腦脑膿脓" +
+	"臠脔腳脚脫脱腡脶臉脸臘腊醃腌膩腻騰腾臏膑艦舰艙舱艫舻艱艰豔艳藝艺蕪芜蘆芦蘇苏蘋苹莖茎蔦茑塋茔煢茕藥药蓮莲萵莴獲获鶯莺蒓莼" +
+	"蘿萝螢萤營营縈萦蕭萧薩萨蔥葱蕆蒇蕢蒉蔣蒋蔞蒌藍蓝薊蓟蘺蓠蕷蓣鎣蓥驀蓦薔蔷蘞蔹藺蔺藹蔼蘄蕲蘊蕴藪薮蘚藓虜虏慮虑蟲虫虯虬蝦虾" +
+	"雖虽螞蚂蠶蚕蟻蚁蠣蛎蠱蛊蠔蚝蛺蛱蟯蛲螄蛳蠐蛴蝸蜗蠟蜡蠅蝇蟬蝉蠍蝎螻蝼蠑蝾襪袜襯衬袞衮襖袄裊袅褲裤襝裣襠裆褸褛襤褴見见觀观" +
+	"規规覓觅視视覘觇覽览覺觉覬觊覡觋覲觐覷觑觸触觴觞訂订計计訊讯討讨訓训記记訕讪託托訖讫訝讶訥讷許许設设訟讼訛讹訪访訣诀證证" +
+	"評评詛诅識识詐诈訴诉診诊詞词譯译試试詩诗詰诘誠诚話话誕诞詭诡詢询該该詳详誅诛誄诔誆诓認认誑诳誨诲說说誦诵請请諸诸諾诺讀读" +
+	"課课誰谁調调諂谄諒谅談谈誼谊謀谋諜谍謊谎謎谜諧谐謔谑謁谒謂谓諭谕諮谘謙谦講讲謝谢謠谣謬谬譜谱謹谨譴谴議议讓让讚赞讒谗豬猪" +
+	"貝贝貞贞負负貢贡財财責责賢贤敗败賬账貨货質质販贩貪贪貧贫購购貯贮貫贯貳贰賤贱賁贲貼贴貴贵貸贷貿贸費费賀贺賊贼賄贿資资賈贾" +
+	"賂赂賃赁賠赔賞赏賜赐賦赋賭赌賴赖賺赚賽赛贈赠贊赞贏赢贍赡贓赃趙赵趕赶趨趋躍跃蹌跄踐践蹺跷蹤踪蹣蹒躊踌躋跻躑踯躪躏軀躯車车" +
+	"軋轧軌轨軒轩轉转輪轮軟软轟轰軸轴軼轶軻轲輕轻載载輊轾較较輔辅輛辆輝辉輩辈輥辊輯辑輸输轅辕轄辖輾辗轍辙辭辞辯辩邊边遼辽達达" +
+	"遷迁過过邁迈運运還还這这進进遠远違违連连遲迟適适選选遜逊遞递邏逻遺遗遙遥鄧邓鄰邻鄭郑鄒邹鄴邺郵邮醞酝醬酱釀酿釋释鑒鉴針针" +
+	"釘钉釣钓鈣钙鈍钝鈔钞鈕钮鈞钧鈴铃鉛铅鉤钩鉀钾鉗钳鈾铀銀银銅铜銘铭銳锐鋁铝鋼钢錢钱錯错錫锡鍋锅鍵键鍍镀鎖锁鎮镇鏡镜鏈链鐘钟" +
+	"鍾钟鐵铁鑄铸鑰钥鑲镶鑽钻長长門门閃闪閉闭問问閑闲間间悶闷閘闸鬧闹閣阁閥阀閨闺閩闽閱阅闊阔闆板闖闯闡阐闢辟隊队陽阳陰阴陣阵" +
+	"階阶際际陸陆陳陈險险隨随隱隐隸隶難难雛雏雞鸡霧雾霽霁靂雳靄霭靜静靦腼韁缰韓韩韌韧韋韦韜韬頁页頂顶頃顷項项順顺須须預预頑顽" +
+	"頒颁頓顿頌颂領领頗颇頻频顆颗題题額额顏颜類类顧顾顫颤風风颱台颳刮颶飓飄飘飛飞飢饥飯饭飲饮飼饲飽饱飾饰餃饺餅饼餓饿館馆饅馒" +
+	"饒饶馬马馭驭馳驰駁驳駐驻駕驾駛驶駝驼騎骑騙骗驅驱驗验驕骄驟骤骯肮髏髅髒脏鬆松鬍胡鬚须魚鱼魯鲁鮮鲜鯉鲤鯨鲸鰻鳗鱗鳞鳥鸟鳴鸣" +
+	"鴉鸦鴨鸭鴿鸽鵝鹅鵬鹏鶴鹤鷹鹰鸚鹦鹵卤鹹咸麥麦黃黄黴霉齊齐齒齿齡龄龍龙龜龟裏里裡里麼么後后隻只準准製制係系繫系彙汇麵面鍊链" +
+	"穀谷擔担禦御嚮向遊游週周鬨哄傢家鈎钩箇个丟丢鑑鉴聞闻銷销鋪铺錦锦錶表鐳镭鍛锻鎚锤錘锤鋸锯鏟铲鑼锣閒闲飆飙燄焰"
+
+var t2s = newT2S(t2sTable)
+
+func newT2S(table string) map[rune]rune {
+	runes := []rune(table)
+	m := make(map[rune]rune, len(runes)/2)
+	for i := 0; i+1 < len(runes); i += 2 {
+		m[runes[i]] = runes[i+1]
+	}
+	return m
+}
+
+// simplified 把繁体字转为简体字, 其他字符保持原样
+func simplified(name string) string {
+	return strings.Map(func(r rune) rune {
+		if s, ok := t2s[r]; ok {
+			return s
+		}
+		return r
+	}, name)
+}
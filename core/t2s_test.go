@@ -0,0 +1,53 @@
+package core
+
+import "testing"
+
+func TestSimplified(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"開始遊戲", "开始游戏"},
+		{"確定", "确定"},
+		{"确定", "确定"},
+		{"設定按鈕_2x", "设定按钮_2x"},
+	}
+	for _, tt := range tests {
+		if got := simplified(tt.name); got != tt.want {
+			t.Errorf("simplified(%s) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// 对照表成对排列, 每个繁体字只出现一次, 转换后的字不需要再转换
+func TestT2STable(t *testing.T) {
+	runes := []rune(t2sTable)
+	if len(runes)%2 != 0 {
+		t.Fatalf("t2sTable has odd length %d", len(runes))
+	}
+	seen := map[rune]bool{}
+	for i := 0; i < len(runes); i += 2 {
+		from, to := runes[i], runes[i+1]
+		if seen[from] {
+			t.Errorf("%c listed twice", from)
+		}
+		seen[from] = true
+		if from == to {
+			t.Errorf("%c maps to itself", from)
+		}
+	}
+	for i := 1; i < len(runes); i += 2 {
+		if s, ok := t2s[runes[i]]; ok {
+			t.Errorf("%c -> %c is converted again to %c", runes[i-1], runes[i], s)
+		}
+	}
+}
+
+// 繁体和简体写法的文件名和词典中的词得到相同的结果
+func TestPlanFileTraditional(t *testing.T) {
+	testPlanFile(t, []planFileCase{
+		{"traditional", ChopperCfg{}, "@按钮-確定.png", "btn_que4ding4.png", nil, 0, false},
+		{"traditional dir", ChopperCfg{Dirs: true}, "主介面/@按钮-開始遊戲.png", "zhu3jie4mian4/btn_kai1shi3you2xi4.png", nil, 0, false},
+		{"dictionary", ChopperCfg{Overrides: []Override{{Phrase: "確定", English: "ok"}}}, "@按钮-确定.png", "btn_ok.png", nil, 0, false},
+	})
+}
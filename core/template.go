@@ -0,0 +1,257 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrorTemplateNotUnique = errors.New("命名模板需要包含 {name}, {index} 或 {hash} 之一, 否则文件名会重复")
+
+	regTemplateVar      = regexp.MustCompile(`\{(\w+)\}`)
+	regTemplateOptional = regexp.MustCompile(`\[([^\[\]]*)\]`)
+	regRepeatedJoiner   = regexp.MustCompile(`_{2,}|-{2,}`)
+)
+
+// 命名模板中可以使用的变量, 另外 {dir1} 到 {dir9} 为从上往下第几级文件夹名
+var templateVars = map[string]string{
+	"path":  "文件所在的文件夹, 相对于资源目录",
+	"dir":   "文件所在的文件夹名",
+	"type":  "前缀对应的类型标签",
+	"name":  "转换后的名字, 不含类型标签和扩展名",
+	"scale": "倍图的倍数, 模板中没有时按配置的倍图后缀加在文件名后",
+	"index": "在同一个文件夹中的序号, 从 1 开始, 已经导出的文件占用的序号不再使用",
+	"hash":  "第一次导出前原文件内容 sha256 的前 8 位",
+}
+
+// nameParts 计算模板需要的文件名各部分, 文件夹已经转换
+type nameParts struct {
+	dir  string
+	tag  string
+	name string
+	ext  string
+}
+
+// nameTemplate 例如 {dir}_{type}_{name}, {type}/{name}[@{scale}x]
+// [ ] 中的部分只有其中的变量都不为空时才保留, 结果相对于文件所在的文件夹
+type nameTemplate struct {
+	text  string
+	index bool
+	hash  bool
+	scale bool
+}
+
+func isTemplateVar(name string) bool {
+	if _, ok := templateVars[name]; ok {
+		return true
+	}
+	if strings.HasPrefix(name, "dir") {
+		n, err := strconv.Atoi(name[3:])
+		return err == nil && n >= 1 && n <= 9
+	}
+	return false
+}
+
+func parseTemplate(text string) (*nameTemplate, error) {
+	t := &nameTemplate{text: text}
+	unique := false
+	for _, m := range regTemplateVar.FindAllStringSubmatch(text, -1) {
+		if !isTemplateVar(m[1]) {
+			return nil, fmt.Errorf("命名模板中未知的变量: {%s}", m[1])
+		}
+		switch m[1] {
+		case "name":
+			unique = true
+		case "index":
+			t.index, unique = true, true
+		case "hash":
+			t.hash, unique = true, true
+		case "scale":
+			t.scale = true
+		}
+	}
+	if !unique {
+		return nil, ErrorTemplateNotUnique
+	}
+	return t, nil
+}
+
+func (t *nameTemplate) render(vars map[string]string) string {
+	replace := func(s string) (string, bool) {
+		full := true
+		s = regTemplateVar.ReplaceAllStringFunc(s, func(m string) string {
+			value := vars[m[1:len(m)-1]]
+			full = full && value != ""
+			return value
+		})
+		return s, full
+	}
+	s := regTemplateOptional.ReplaceAllStringFunc(t.text, func(m string) string {
+		s, full := replace(m[1 : len(m)-1])
+		if !full {
+			return ""
+		}
+		return s
+	})
+	s, _ = replace(s)
+
+	// 去掉空变量留下的多余连接符和空的文件夹
+	var segments []string
+	for _, segment := range strings.Split(s, "/") {
+		segment = regRepeatedJoiner.ReplaceAllStringFunc(segment, func(m string) string {
+			return m[:1]
+		})
+		segment = strings.Trim(segment, "_-")
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// 计算已经使用的序号时代替 {index} 的字符
+const indexMark = "\uE000"
+
+// renderTemplate 按模板计算所有文件的新文件名, {index} 和 {hash} 需要所有文件的信息
+func (n *namer) renderTemplate(ctx context.Context, cfg ChopperCfg, items []PlanItem) error {
+	t := n.template
+	if t == nil {
+		return nil
+	}
+	// 已经导出的文件保持现在的名字, 其他文件的位置都已经被占用
+	var indexes []int
+	taken := map[string][]string{}
+	for i := range items {
+		if items[i].parts != nil && items[i].Error == "" && items[i].origin == "" {
+			indexes = append(indexes, i)
+			continue
+		}
+		key := nameKey(planTarget(items[i]))
+		taken[path.Dir(key)] = append(taken[path.Dir(key)], key)
+	}
+
+	hashes := make([]string, len(indexes))
+	if t.hash {
+		errs := make([]error, len(indexes))
+		err := parallel(ctx, workerCount(cfg), len(indexes), func(k int) {
+			var hash string
+			hash, errs[k] = fileHash(path.Join(cfg.DirPath, items[indexes[k]].From))
+			if errs[k] == nil {
+				hashes[k] = hash[:8]
+			}
+		})
+		if err != nil {
+			return err
+		}
+		for k, err := range errs {
+			if err != nil {
+				items[indexes[k]].Error = err.Error()
+			}
+		}
+	}
+
+	vars := make([]map[string]string, len(indexes))
+	for k, i := range indexes {
+		p := items[i].parts
+		vars[k] = map[string]string{
+			"type": p.tag,
+			"name": p.name,
+			"hash": hashes[k],
+		}
+		if items[i].Retina > 0 {
+			vars[k]["scale"] = strconv.Itoa(items[i].Retina)
+		}
+		if p.dir != "." {
+			vars[k]["path"] = p.dir
+			vars[k]["dir"] = path.Base(p.dir)
+			for level, segment := range strings.Split(p.dir, "/") {
+				if level < 9 {
+					vars[k]["dir"+strconv.Itoa(level+1)] = segment
+				}
+			}
+		}
+	}
+
+	// 同一个文件夹中的文件按原文件名排序编号, 从已经导出的文件占用的最大序号之后继续
+	if t.index {
+		order := make([]int, len(indexes))
+		for k := range order {
+			order[k] = k
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			return items[indexes[order[a]]].From < items[indexes[order[b]]].From
+		})
+		counts := map[string]int{}
+		used := map[string]int{}
+		for _, k := range order {
+			item := &items[indexes[k]]
+			if item.Error != "" {
+				continue
+			}
+			vars[k]["index"] = indexMark
+			pattern := nameKey(n.templateName(item, vars[k]))
+			max, ok := used[pattern]
+			if !ok {
+				max = usedIndex(pattern, taken)
+				used[pattern] = max
+			}
+			dir := item.parts.dir
+			counts[dir]++
+			if counts[dir] <= max {
+				counts[dir] = max + 1
+			}
+			vars[k]["index"] = strconv.Itoa(counts[dir])
+		}
+	}
+
+	for k, i := range indexes {
+		item := &items[i]
+		if item.Error != "" {
+			continue
+		}
+		item.To = n.templateName(item, vars[k])
+	}
+	return nil
+}
+
+// templateName 按模板和变量计算文件的新文件名
+func (n *namer) templateName(item *PlanItem, vars map[string]string) string {
+	t := n.template
+	name := t.render(vars)
+	if !t.scale {
+		name += n.retinaSuffix(item.Retina)
+	}
+	if n.lowercase {
+		name = strings.ToLower(name)
+	}
+	return path.Join(item.parts.dir, name+item.parts.ext)
+}
+
+// usedIndex 已经被占用的文件名中按 pattern 得到的最大序号, pattern 中的 {index} 为 indexMark
+func usedIndex(pattern string, taken map[string][]string) int {
+	reg := regexp.MustCompile("^" + strings.Replace(regexp.QuoteMeta(pattern), indexMark, `(\d+)`, 1) + "$")
+	names := taken[path.Dir(pattern)]
+	if strings.Contains(path.Dir(pattern), indexMark) {
+		names = nil
+		for _, list := range taken {
+			names = append(names, list...)
+		}
+	}
+	max := 0
+	for _, name := range names {
+		m := reg.FindStringSubmatch(name)
+		if m == nil {
+			continue
+		}
+		if index, err := strconv.Atoi(m[1]); err == nil && index > max {
+			max = index
+		}
+	}
+	return max
+}
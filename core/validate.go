@@ -0,0 +1,159 @@
+package core
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 文件名不符合规则时的处理方式
+const (
+	// ValidateBlock 不导出这个文件, 记为错误
+	ValidateBlock = "block"
+	// ValidateWarn 照常导出, 只给出警告
+	ValidateWarn = "warn"
+)
+
+var ValidateActions = []string{ValidateBlock, ValidateWarn}
+
+// ValidationCfg 导出后文件名的检查规则, 都为空时不检查
+type ValidationCfg struct {
+	// 文件名 (不含扩展名) 需要匹配的正则, 例如 ^[a-z_][a-z0-9_]*$
+	Pattern string `json:"pattern,omitempty"`
+	// 文件名 (含扩展名) 的最大字符数, 0 表示不限制
+	MaxLength int `json:"max_length,omitempty"`
+	// 导出的文件名全部转为小写
+	Lowercase bool `json:"lowercase,omitempty"`
+	// 不能使用的文件名 (不含扩展名), 不区分大小写
+	Reserved []string `json:"reserved,omitempty"`
+	// 见 ValidateActions, 默认 ValidateBlock
+	Action string `json:"action,omitempty"`
+}
+
+// validator 检查计划中的文件名
+type validator struct {
+	pattern   *regexp.Regexp
+	maxLength int
+	reserved  map[string]bool
+	warn      bool
+	// 同时检查转换后的文件夹名
+	dirs bool
+	// 每张图片需要有的倍数, 见 RetinaCfg
+	retina []int
+	// 拼音风格带声调符号, 允许 ā 这样的拉丁字母
+	toneMarks bool
+}
+
+func newValidator(chopperCfg ChopperCfg) (*validator, error) {
+	cfg := chopperCfg.Validation
+	v := &validator{
+		maxLength: cfg.MaxLength,
+		reserved:  map[string]bool{},
+		warn:      cfg.Action == ValidateWarn,
+		dirs:      chopperCfg.Dirs,
+		retina:    chopperCfg.Retina.Required,
+		toneMarks: chopperCfg.PinyinStyle == "tone" || chopperCfg.PinyinStyle == "finals_tone",
+	}
+	if cfg.Action != "" && cfg.Action != ValidateBlock && cfg.Action != ValidateWarn {
+		return nil, fmt.Errorf("未知的文件名检查方式: %s", cfg.Action)
+	}
+	if cfg.Pattern != "" {
+		var err error
+		v.pattern, err = regexp.Compile(cfg.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("文件名规则不是有效的正则: %w", err)
+		}
+	}
+	for _, scale := range chopperCfg.Retina.Required {
+		if scale < 1 {
+			return nil, fmt.Errorf("倍图的倍数需要大于 0: %d", scale)
+		}
+	}
+	for _, word := range cfg.Reserved {
+		v.reserved[strings.ToLower(word)] = true
+	}
+	return v, nil
+}
+
+// check 返回文件名不符合的规则, 文件夹名没有扩展名
+func (v *validator) check(name string, dir bool) []string {
+	var problems []string
+	base := name
+	if !dir {
+		base = strings.TrimSuffix(name, path.Ext(name))
+	}
+	if v.pattern != nil && !v.pattern.MatchString(base) {
+		problems = append(problems, fmt.Sprintf("%s 不符合规则 %s", base, v.pattern))
+	}
+	if v.maxLength > 0 && utf8.RuneCountInString(name) > v.maxLength {
+		problems = append(problems, fmt.Sprintf("%s 超过 %d 个字符", name, v.maxLength))
+	}
+	if v.reserved[strings.ToLower(base)] {
+		problems = append(problems, fmt.Sprintf("%s 是保留的文件名", base))
+	}
+	return problems
+}
+
+// unconverted 返回转换后仍然残留的非 ASCII 字符, 例如拼音库中没有的汉字
+func (v *validator) unconverted(name string) string {
+	var chars []rune
+	seen := map[rune]bool{}
+	for _, r := range name {
+		if r < utf8.RuneSelf || seen[r] || v.toneMarks && unicode.Is(unicode.Latin, r) {
+			continue
+		}
+		seen[r] = true
+		chars = append(chars, r)
+	}
+	return string(chars)
+}
+
+// validate 检查计划中所有的新文件名, 按配置记为错误或警告
+func (v *validator) validate(plan *Plan) {
+	for i := range plan.Items {
+		v.validateItem(&plan.Items[i])
+	}
+}
+
+// validateItem 检查一个文件的新文件名, 转换后仍有非 ASCII 字符的文件总是记为错误
+func (v *validator) validateItem(item *PlanItem) {
+	if item.Error != "" {
+		return
+	}
+	if item.converted {
+		name := path.Base(item.To)
+		if v.dirs {
+			name = item.To
+		}
+		if chars := v.unconverted(name); chars != "" {
+			item.Error = fmt.Sprintf("%s 中有无法转换的字符: %s", name, chars)
+			return
+		}
+	}
+	problems := v.check(path.Base(item.To), false)
+	if v.dirs && path.Dir(item.To) != "." {
+		for _, segment := range strings.Split(path.Dir(item.To), "/") {
+			for _, problem := range v.check(segment, true) {
+				problems = append(problems, "文件夹 "+problem)
+			}
+		}
+	}
+	v.report(item, problems)
+}
+
+// report 按配置把不符合的规则记为错误或警告
+func (v *validator) report(item *PlanItem, problems []string) {
+	if len(problems) == 0 {
+		return
+	}
+	if v.warn {
+		item.Warnings = append(item.Warnings, problems...)
+	} else if item.Error == "" {
+		item.Error = strings.Join(problems, "; ")
+	} else {
+		item.Error += "; " + strings.Join(problems, "; ")
+	}
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		validation ValidationCfg
+		file       string
+		to         string
+		err        string
+		warning    bool
+	}{
+		{"pass", ValidationCfg{Pattern: "^[a-z0-9_]+$"}, "@按钮-确定.png", "btn_que4ding4.png", "", false},
+		{"pattern block", ValidationCfg{Pattern: "^[a-z_]+$"}, "@按钮-确定.png", "btn_que4ding4.png", "不符合规则", false},
+		{"pattern warn", ValidationCfg{Pattern: "^[a-z_]+$", Action: ValidateWarn}, "@按钮-确定.png", "btn_que4ding4.png", "", true},
+		{"max length", ValidationCfg{MaxLength: 10}, "@按钮-确定.png", "btn_que4ding4.png", "超过 10 个字符", false},
+		{"reserved", ValidationCfg{Reserved: []string{"BTN_QUE4DING4"}}, "@按钮-确定.png", "btn_que4ding4.png", "保留的文件名", false},
+		{"lowercase", ValidationCfg{Lowercase: true}, "@按钮-OK.png", "btn_ok.png", "", false},
+		// 不需要转换的文件保持原名
+		{"lowercase other type", ValidationCfg{Lowercase: true}, "Config.JSON", "Config.JSON", "", false},
+		{"unconverted", ValidationCfg{}, "@按钮-確定ㄅ.png", "", "无法转换的字符", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := ChopperCfg{Validation: tt.validation}
+			n, err := newNamer(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, err := newValidator(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			plan := &Plan{Items: []PlanItem{n.planFile(tt.file)}}
+			v.validate(plan)
+			item := plan.Items[0]
+			if tt.to != "" && item.To != tt.to {
+				t.Errorf("to = %s, want %s", item.To, tt.to)
+			}
+			if tt.err == "" && item.Error != "" || !strings.Contains(item.Error, tt.err) {
+				t.Errorf("error = %q, want %q", item.Error, tt.err)
+			}
+			if tt.warning != (len(item.Warnings) > 0) {
+				t.Errorf("warnings = %v", item.Warnings)
+			}
+		})
+	}
+
+	if _, err := newValidator(ChopperCfg{Validation: ValidationCfg{Pattern: "["}}); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+	if _, err := newValidator(ChopperCfg{Validation: ValidationCfg{Action: "ignore"}}); err == nil {
+		t.Error("unknown action accepted")
+	}
+}
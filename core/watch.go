@@ -0,0 +1,213 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// StageWatch 监视模式发出的事件, 不是可以配置的阶段
+const StageWatch = "watch"
+
+// DefaultWatchDelay 最后一次变动后等待多久再导出, Sketch 导出切图时会在短时间内写入大量文件
+const DefaultWatchDelay = 2 * time.Second
+
+// 撤销或自动导出结束后这段时间内的变动仍然当作它们产生的, fsnotify 的事件可能稍晚送达
+const watchSettle = time.Second
+
+// WatchRun 一次自动导出的结果
+type WatchRun struct {
+	Time time.Time
+	// 触发这次导出的变动文件数量
+	Changes int
+	Result  *Result
+	Err     error
+}
+
+// Watcher 监视 ChopperCfg.DirPath 的变动, 变动停止 Delay 之后自动导出
+// 和 walkDir 一样忽略 . 和 __ 开头的文件和文件夹, 包括 .remote 和 .chopper
+type Watcher struct {
+	Cfg   ChopperCfg
+	Delay time.Duration
+	// 导出过程中的进度事件, 同 Exporter.OnEvent
+	OnEvent func(Event)
+	// 每次自动导出结束后调用
+	OnRun func(WatchRun)
+}
+
+func NewWatcher(cfg ChopperCfg) *Watcher {
+	return &Watcher{
+		Cfg:   cfg,
+		Delay: DefaultWatchDelay,
+	}
+}
+
+// watchIgnored 相对路径中任意一级以 . 或 __ 开头时忽略
+func watchIgnored(rel string) bool {
+	for _, name := range strings.Split(filepath.ToSlash(rel), "/") {
+		if strings.HasPrefix(name, ".") && name != "." || strings.HasPrefix(name, "__") {
+			return true
+		}
+	}
+	return false
+}
+
+// addDirs 监视 dir 和它所有没有被忽略的子文件夹, fsnotify 不会递归监视
+func (w *Watcher) addDirs(fw *fsnotify.Watcher, dir string) error {
+	return filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			// 遍历过程中被删除的文件夹
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(w.Cfg.DirPath, file)
+		if err != nil {
+			return err
+		}
+		if watchIgnored(rel) {
+			return filepath.SkipDir
+		}
+		return fw.Add(file)
+	})
+}
+
+func (w *Watcher) emit(message string) {
+	if w.OnEvent != nil {
+		w.OnEvent(Event{Stage: StageWatch, Message: message})
+	}
+}
+
+// Run 开始监视, 直到 ctx 取消; 正在进行的导出也会随 ctx 取消
+func (w *Watcher) Run(ctx context.Context) error {
+	cfg := w.Cfg
+	if cfg.DirPath == "" {
+		return ErrorNoDirPath
+	}
+	f, err := os.Stat(cfg.DirPath)
+	if err != nil {
+		return err
+	}
+	if !f.IsDir() {
+		return ErrorNotDir
+	}
+	delay := w.Delay
+	if delay <= 0 {
+		delay = DefaultWatchDelay
+	}
+
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fw.Close()
+	err = w.addDirs(fw, cfg.DirPath)
+	if err != nil {
+		return err
+	}
+	w.emit("正在监视 " + cfg.DirPath)
+
+	timer := time.NewTimer(delay)
+	timer.Stop()
+	changed := map[string]bool{}
+	// 上一次自动导出会修改的文件和导出结束的时间
+	var exported map[string]bool
+	var exportedAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case err, ok := <-fw.Errors:
+			if !ok {
+				return nil
+			}
+			w.emit(err.Error())
+		case event, ok := <-fw.Events:
+			if !ok {
+				return nil
+			}
+			rel, err := filepath.Rel(cfg.DirPath, event.Name)
+			if err != nil || watchIgnored(rel) {
+				continue
+			}
+			if event.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := w.addDirs(fw, event.Name); err != nil {
+						w.emit(err.Error())
+					}
+				}
+			}
+			// 撤销恢复的文件名不能再被自动导出改回去
+			if undoing(cfg.DirPath, watchSettle) {
+				continue
+			}
+			// 自动导出自己的改名和压缩不再触发导出, 否则后面的阶段失败时会不断重复导出
+			rel = filepath.ToSlash(rel)
+			if exported[rel] && time.Since(exportedAt) < watchSettle {
+				continue
+			}
+			changed[rel] = true
+			// 每次变动都重新计时, 变动停止后才导出
+			timer.Stop()
+			select {
+			case <-timer.C:
+			default:
+			}
+			timer.Reset(delay)
+		case <-timer.C:
+			changes := len(changed)
+			changed = map[string]bool{}
+			exported = w.export(ctx, changes)
+			exportedAt = time.Now()
+		}
+	}
+}
+
+// export 执行一次自动导出, 没有需要处理的文件时不导出, 返回导出可能修改的文件
+func (w *Watcher) export(ctx context.Context, changes int) map[string]bool {
+	exporter := NewExporter(w.Cfg)
+	exporter.OnEvent = w.OnEvent
+	run := WatchRun{
+		Time:    time.Now(),
+		Changes: changes,
+	}
+	touched := map[string]bool{}
+	plan, err := exporter.Plan(ctx)
+	if err == nil && plan.Changed() == 0 {
+		return touched
+	}
+	if err == nil {
+		for _, item := range plan.Items {
+			if item.Unchanged {
+				continue
+			}
+			touched[item.From+".chopper-tmp"] = true
+			// 改名时会新建和删除文件夹
+			for _, file := range []string{item.From, item.To} {
+				for ; file != "."; file = path.Dir(file) {
+					touched[file] = true
+				}
+			}
+		}
+		run.Result, err = exporter.Apply(ctx, plan)
+	}
+	// 手动导出正在处理同一个文件夹, 不需要重复导出
+	if ctx.Err() != nil || err == ErrorBusy {
+		return touched
+	}
+	run.Err = err
+	if w.OnRun != nil {
+		w.OnRun(run)
+	}
+	return touched
+}
@@ -1,406 +1,255 @@
 package main
 
 import (
-	"errors"
+	"context"
 	"fmt"
-	"image"
-	"image/color"
-	"io"
-	"io/ioutil"
-	"log"
-	"os"
-	"os/exec"
-	"path"
-	"regexp"
-	"strconv"
+	"net/url"
 	"strings"
-	"time"
 
 	"fyne.io/fyne"
 	"fyne.io/fyne/dialog"
-	"github.com/disintegration/imaging"
-	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/plumbing/object"
-	"github.com/go-git/go-git/v5/plumbing/transport/http"
-	"github.com/mozillazg/go-pinyin"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/widget"
+	"github.com/dragon8897/chopper/core"
 )
 
-var (
-	ErrorNotFoundImageOptim = errors.New("没有找到压缩工具")
-)
-
-func walkDir(dir string, base string) (files []string, err error) {
-	dirs, err := ioutil.ReadDir(dir)
-	if err != nil {
-		return nil, err
+func createPlanUI(plan *core.Plan) fyne.CanvasObject {
+	cells := []fyne.CanvasObject{
+		widget.NewLabelWithStyle("原文件", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle("新文件", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle("九宫格", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle("git", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 	}
-	for _, file := range dirs {
-		name := file.Name()
-		if strings.HasPrefix(name, "__") {
+	for _, item := range plan.Items {
+		if item.Unchanged || item.Error != "" {
 			continue
 		}
-		if strings.HasPrefix(name, ".") {
-			continue
+		to := "-"
+		if item.Renamed() {
+			to = item.To
 		}
-		filePath := path.Join(dir, name)
-		basePath := path.Join(base, name)
-		if file.IsDir() {
-			subFiles, err := walkDir(filePath, basePath)
-			if err != nil {
-				return nil, err
-			}
-			files = append(files, subFiles...)
-		} else {
-			files = append(files, basePath)
+		if len(item.Overrides) > 0 {
+			to += "\n词典: " + strings.Join(item.Overrides, ", ")
 		}
-	}
-	return files, nil
-}
-
-func handle9Scale(file string, left int, top int, right int, bottom int) {
-	src, err := imaging.Open(file)
-	if err != nil {
-		log.Fatalf("failed to open image: %v", err)
-		return
-	}
-
-	src_tl := imaging.CropAnchor(src, left, top, imaging.TopLeft)
-	src_tr := imaging.CropAnchor(src, right, top, imaging.TopRight)
-	src_bl := imaging.CropAnchor(src, left, bottom, imaging.BottomLeft)
-	src_br := imaging.CropAnchor(src, right, bottom, imaging.BottomRight)
-
-	dst := imaging.New(left+right, top+bottom, color.NRGBA{0, 0, 0, 0})
-	dst = imaging.Paste(dst, src_tl, image.Pt(0, 0))
-	dst = imaging.Paste(dst, src_tr, image.Pt(left, 0))
-	dst = imaging.Paste(dst, src_bl, image.Pt(0, top))
-	dst = imaging.Paste(dst, src_br, image.Pt(left, top))
-
-	err = imaging.Save(dst, file)
-	if err != nil {
-		log.Fatalf("failed to save image: %v", err)
-	}
+		scale := "-"
+		if item.Scale != nil {
+			scale = fmt.Sprint(item.Scale)
+		}
+		change := "-"
+		if item.Git != "" {
+			change = item.Git
+		}
+		cells = append(cells,
+			widget.NewLabel(item.From),
+			widget.NewLabel(to),
+			widget.NewLabel(scale),
+			widget.NewLabel(change),
+		)
+	}
+	table := fyne.NewContainerWithLayout(layout.NewGridLayout(4), cells...)
+	box := widget.NewVBox(createInvalidUI(plan), createConflictsUI(plan.Conflicts), table)
+	if unchanged := len(plan.Items) - plan.Changed(); unchanged > 0 {
+		box.Append(widget.NewLabel(fmt.Sprintf("另有 %d 个文件和上次导出相比没有变化", unchanged)))
+	}
+	scroll := widget.NewScrollContainer(box)
+	scroll.SetMinSize(fyne.NewSize(600, 300))
+	return scroll
 }
 
-func copyFile(src, dst string) (err error) {
-	in, err := os.Open(src)
-	if err != nil {
-		return
-	}
-	defer in.Close()
-
-	_ = os.MkdirAll(path.Dir(dst), os.ModePerm)
-	out, err := os.Create(dst)
-	if err != nil {
-		return
-	}
-	defer func() {
-		if e := out.Close(); e != nil {
-			err = e
+// createInvalidUI 文件名无法处理的文件和有警告的文件, 无法处理的文件不会被导出
+func createInvalidUI(plan *core.Plan) fyne.CanvasObject {
+	box := widget.NewVBox()
+	if plan.Invalid() > 0 {
+		box.Append(widget.NewLabelWithStyle(fmt.Sprintf("无法处理的文件 %d:", plan.Invalid()), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+		for _, item := range plan.Items {
+			if item.Error != "" {
+				box.Append(widget.NewLabel(item.From + ": " + item.Error))
+			}
 		}
-	}()
-
-	_, err = io.Copy(out, in)
-	if err != nil {
-		return
 	}
-
-	err = out.Sync()
-	if err != nil {
-		return
-	}
-
-	si, err := os.Stat(src)
-	if err != nil {
-		return
+	if plan.Warned() > 0 {
+		box.Append(widget.NewLabelWithStyle(fmt.Sprintf("文件名警告 %d:", plan.Warned()), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+		for _, item := range plan.Items {
+			for _, warning := range item.Warnings {
+				box.Append(widget.NewLabel(item.From + ": " + warning))
+			}
+		}
 	}
-	err = os.Chmod(dst, si.Mode())
-	if err != nil {
-		return
+	if terms := plan.Untranslated(); len(terms) > 0 {
+		box.Append(widget.NewLabelWithStyle(fmt.Sprintf("未翻译的词 %d:", len(terms)), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+		for _, term := range terms {
+			box.Append(widget.NewLabel(fmt.Sprintf("%s (%d): %s", term.Term, len(term.Files), strings.Join(term.Files, ", "))))
+		}
 	}
-
-	return
+	return box
 }
 
-func execute(name string, arg ...string) error {
-	cmd := exec.Command(name, arg...)
-	err := cmd.Start()
-	if err != nil {
-		return err
+func createConflictsUI(conflicts []core.Conflict) fyne.CanvasObject {
+	box := widget.NewVBox()
+	if len(conflicts) == 0 {
+		return box
 	}
-	err = cmd.Wait()
-	if err != nil {
-		return err
+	box.Append(widget.NewLabelWithStyle(fmt.Sprintf("改名冲突 %d:", len(conflicts)), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+	for _, c := range conflicts {
+		resolution := c.Resolution
+		if resolution == "" {
+			resolution = "未处理"
+		}
+		box.Append(widget.NewLabel(fmt.Sprintf("%s (%s)\n    %s", c.To, resolution, strings.Join(c.Files, "\n    "))))
 	}
-
-	return nil
+	return box
 }
 
-func compressImage(images ...string) error {
-	imageOptim := path.Join("/Applications/ImageOptim.app/Contents/MacOS", "ImageOptim")
-	_, err := os.Stat(imageOptim)
-	if err != nil {
-		return ErrorNotFoundImageOptim
-	}
-	return execute(imageOptim, images...)
-}
+// export 在进度对话框中计算导出计划, 可以取消, 完成后确认计划再开始导出
+func export(cfg core.ChopperCfg, win fyne.Window) {
+	exporter := core.NewExporter(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	content, onEvent := createProgressUI([]string{core.StageWalk})
+	exporter.OnEvent = onEvent
+	prog := dialog.NewCustom("导出计划", "取消", content, win)
+	prog.SetOnClosed(cancel)
+	prog.Show()
 
-func gitUpload(cfg ChopperCfg, files []string) (git.Status, error) {
-	if len(files) == 0 {
-		return nil, nil
-	}
-	if cfg.Git.Password == "" || cfg.Git.UserName == "" || cfg.Git.URL == "" {
-		return nil, nil
-	}
-	dir := path.Join(cfg.DirPath, ".remote")
-	_, err := os.Stat(dir)
-	if os.IsNotExist(err) {
-		_, err := git.PlainClone(dir, false, &git.CloneOptions{
-			Auth: &http.BasicAuth{
-				Username: cfg.Git.UserName,
-				Password: cfg.Git.Password,
-			},
-			URL: cfg.Git.URL,
-		})
+	go func() {
+		plan, err := exporter.Plan(ctx)
+		canceled := ctx.Err() != nil
+		prog.Hide()
+		if canceled {
+			return
+		}
 		if err != nil {
-			return nil, err
+			dialog.ShowError(err, win)
+			return
 		}
-	}
-	d, err := os.Stat(dir)
-	if err != nil {
-		return nil, err
-	}
-	if !d.IsDir() {
-		return nil, err
-	}
-	r, err := git.PlainOpen(dir)
-	if err != nil {
-		return nil, err
-	}
-	w, err := r.Worktree()
-	if err != nil {
-		return nil, err
-	}
-	ref, err := r.Head()
-	if err != nil {
-		return nil, err
-	}
-	err = w.Reset(&git.ResetOptions{
-		Commit: ref.Hash(),
-		Mode:   git.HardReset,
-	})
-	if err != nil {
-		return nil, err
-	}
-	err = w.Pull(&git.PullOptions{
-		RemoteName: "origin",
-		Auth: &http.BasicAuth{
-			Username: cfg.Git.UserName,
-			Password: cfg.Git.Password,
-		},
-	})
-	if err == git.NoErrAlreadyUpToDate {
-		fmt.Println(err)
-	} else if err != nil {
-		return nil, err
-	}
-
-	for _, f := range files {
-		_ = copyFile(path.Join(cfg.DirPath, f), path.Join(dir, f))
-	}
-
-	s, err := w.Status()
-	if err != nil {
-		return nil, err
-	}
-
-	if len(s) == 0 {
-		return nil, nil
-	}
-
-	_, err = w.Add(".")
-	if err != nil {
-		return nil, err
-	}
+		dialog.ShowCustomConfirm("导出计划", "开始导出", "取消", createPlanUI(plan), func(ok bool) {
+			if ok {
+				apply(exporter, plan, win)
+			}
+		}, win)
+	}()
+}
 
-	_, err = w.Commit("update res", &git.CommitOptions{
-		Author: &object.Signature{
-			Name:  "chopper",
-			Email: "chopper@didiapp.com",
-			When:  time.Now(),
-		},
-	})
+func createSummaryUI(res *core.Result, err error) fyne.CanvasObject {
+	box := widget.NewVBox()
 	if err != nil {
-		return nil, err
+		box.Append(widget.NewLabelWithStyle(err.Error(), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
 	}
-
-	err = r.Push(&git.PushOptions{
-		Auth: &http.BasicAuth{
-			Username: cfg.Git.UserName,
-			Password: cfg.Git.Password,
-		},
-	})
-
-	if err != nil {
-		return nil, err
+	if res != nil {
+		if len(res.Uploaded) == 0 && len(res.Errors) == 0 {
+			box.Append(widget.NewLabel("没有可更新的文件!"))
+		}
+		box.Append(widget.NewLabel(res.Summary()))
+		box.Append(createConflictsUI(res.Conflicts))
+		if res.Report != "" {
+			report := &url.URL{Scheme: "file", Path: res.Report}
+			box.Append(widget.NewButton("打开报告", func() {
+				_ = fyne.CurrentApp().OpenURL(report)
+			}))
+		}
 	}
+	scroll := widget.NewScrollContainer(box)
+	scroll.SetMinSize(fyne.NewSize(500, 200))
+	return scroll
+}
 
-	return s, nil
+var stageTitles = map[string]string{
+	core.StageWalk:     "扫描",
+	core.Stage9Scale:   "九宫格",
+	core.StageRename:   "改名",
+	core.StageCompress: "压缩",
+	core.StageUpload:   "上传",
+	core.StageRobot:    "通知",
 }
 
-func export(cfg ChopperCfg, win fyne.Window) {
-	if cfg.DirPath == "" {
-		dialog.NewError(errors.New("目标文件夹没有配置"), win)
-		return
-	}
-	f, err := os.Stat(cfg.DirPath)
-	if err != nil {
-		dialog.NewError(err, win)
-		return
-	}
-	if !f.IsDir() {
-		dialog.NewError(errors.New("目标位置不是一个文件夹"), win)
-		return
-	}
-	prog := dialog.NewProgressInfinite("导出", "正在导出", win)
-	prog.Show()
+type stageRow struct {
+	bar  *widget.ProgressBar
+	info *widget.Label
+}
 
-	files, err := walkDir(cfg.DirPath, "")
-	if err != nil {
-		dialog.NewError(err, win)
-		return
-	}
-	pyArgs := pinyin.NewArgs()
-	pyArgs.Style = pinyin.Tone3
-	pyArgs.Fallback = func(r rune, a pinyin.Args) []string {
-		// 去掉空格
-		if r == 32 {
-			return []string{}
+// createProgressUI 每个阶段一行进度, 返回界面和事件处理函数
+func createProgressUI(stages []string) (fyne.CanvasObject, func(core.Event)) {
+	rows := map[string]*stageRow{}
+	form := fyne.NewContainerWithLayout(layout.NewFormLayout())
+	for _, stage := range stages {
+		title, ok := stageTitles[stage]
+		if !ok {
+			title = stage
+		}
+		row := &stageRow{
+			bar:  widget.NewProgressBar(),
+			info: widget.NewLabel("等待中"),
+		}
+		rows[stage] = row
+		form.AddObject(widget.NewLabel(title + ":"))
+		form.AddObject(widget.NewVBox(row.bar, row.info))
+	}
+	onEvent := func(e core.Event) {
+		row, ok := rows[e.Stage]
+		if !ok {
+			return
+		}
+		if e.File != "" {
+			return
+		}
+		if e.Message != "" {
+			row.info.SetText(e.Message)
+			return
+		}
+		if e.Total > 0 {
+			row.bar.Max = float64(e.Total)
+			row.bar.SetValue(float64(e.Done))
+			row.info.SetText(fmt.Sprintf("%d/%d", e.Done, e.Total))
 		} else {
-			return []string{
-				string(r),
-			}
+			row.info.SetText(fmt.Sprint(e.Done))
 		}
 	}
+	return form, onEvent
+}
 
-	var dstFiles []string
-	regType := regexp.MustCompile(`^@.+?-`)
-	reg9Scale := regexp.MustCompile(`#\([\d|,]+\)`)
-	for _, file := range files {
-		fileName := path.Base(file)
-		fileDir := path.Dir(file)
-		if strings.HasSuffix(fileName, ".png") || strings.HasSuffix(fileName, ".jpg") {
-			targetName := fileName
-
-			// 替换前缀类型: 按钮 -> btn; 背景 -> bg; 图标 -> icon; 预览 -> preview
-			loc := regType.FindStringIndex(targetName)
-			if len(loc) > 0 {
-				typeName := targetName[:loc[1]]
-				typeTag := ""
-				switch typeName {
-				case "@按钮-":
-					typeTag = "btn_"
-				case "@背景-":
-					typeTag = "bg_"
-				case "@图标-":
-					typeTag = "icon_"
-				case "@预览-":
-					typeTag = "preview_"
-				case "@动画-":
-					typeTag = "ani_"
-				}
-				targetName = typeTag + targetName[loc[1]:]
-			}
+func apply(exporter *core.Exporter, plan *core.Plan, win fyne.Window) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stages := exporter.Cfg.Stages
+	if stages == nil {
+		stages = core.DefaultStages
+	}
+	content, onEvent := createProgressUI(stages)
+	exporter.OnEvent = onEvent
+	prog := dialog.NewCustom("导出", "取消", content, win)
+	// 点击取消或导出结束关闭对话框时都会调用, 结束后再取消没有影响
+	prog.SetOnClosed(cancel)
+	prog.Show()
 
-			// 处理九宫格图片
-			loc = reg9Scale.FindStringIndex(targetName)
-			if len(loc) > 0 {
-				// 去掉 @( )
-				scaleTag := targetName[loc[0]+2 : loc[1]-1]
-				scaleStrs := strings.Split(scaleTag, ",")
-				var scaleNums []int
-				for _, s := range scaleStrs {
-					num, err := strconv.Atoi(s)
-					if err == nil {
-						scaleNums = append(scaleNums, num)
-					}
-				}
-				var left, top, right, bottom int
-				if len(scaleNums) == 1 {
-					left, top, right, bottom = scaleNums[0], scaleNums[0], scaleNums[0], scaleNums[0]
-				} else if len(scaleNums) == 2 {
-					left, right = scaleNums[0], scaleNums[0]
-					top, bottom = scaleNums[1], scaleNums[1]
-				} else if len(scaleNums) == 3 {
-					left = scaleNums[0]
-					top, bottom = scaleNums[1], scaleNums[1]
-					right = scaleNums[2]
-				} else {
-					left = scaleNums[0]
-					top = scaleNums[1]
-					right = scaleNums[2]
-					bottom = scaleNums[3]
-				}
-				handle9Scale(path.Join(cfg.DirPath, fileName), left, top, right, bottom)
-				fileBytes := []byte(fileName)
-				targetName = string(append(fileBytes[:loc[0]], fileBytes[loc[1]:]...))
-			}
-			newName := strings.Join(pinyin.LazyPinyin(targetName, pyArgs), "")
-			dstFile := path.Join(cfg.DirPath, fileDir, newName)
-			err = os.Rename(path.Join(cfg.DirPath, fileDir, fileName), dstFile)
-			if err != nil {
-				log.Printf("rename error :%s\n", newName)
-			}
-			dstFiles = append(dstFiles, path.Join(fileDir, newName))
-		} else if strings.HasSuffix(fileName, ".mp3") || strings.HasSuffix(fileName, ".ogg") || strings.HasSuffix(fileName, ".m4a") {
-			newName := strings.Join(pinyin.LazyPinyin(fileName, pyArgs), "")
-			dstFile := path.Join(cfg.DirPath, fileDir, newName)
-			err = os.Rename(path.Join(cfg.DirPath, fileDir, fileName), dstFile)
-			if err != nil {
-				log.Printf("rename error :%s\n", newName)
-			}
-			dstFiles = append(dstFiles, path.Join(fileDir, newName))
-		} else {
-			dstFiles = append(dstFiles, path.Join(fileDir, fileName))
+	go func() {
+		res, err := exporter.Apply(ctx, plan)
+		canceled := ctx.Err() != nil
+		prog.Hide()
+		if canceled {
+			err = fmt.Errorf("导出已取消, 可以撤销已完成的修改: %w", err)
 		}
-	}
-
-	var allImages []string
-	for _, image := range dstFiles {
-		ext := path.Ext(image)
-		if ext != ".png" && ext != ".jpg" {
-			continue
+		if res == nil {
+			dialog.ShowError(err, win)
+			return
 		}
-		allImages = append(allImages, path.Join(cfg.DirPath, image))
-	}
+		dialog.ShowCustom("导出结果", "OK", createSummaryUI(res, err), win)
+	}()
+}
 
-	err = compressImage(allImages...)
-	if err != ErrorNotFoundImageOptim && err != nil {
-		prog.Hide()
-		dialog.NewError(err, win)
+func undo(cfg core.ChopperCfg, win fyne.Window) {
+	j, err := core.LastJournal(cfg)
+	if err != nil {
+		dialog.ShowError(err, win)
 		return
 	}
-
-	uploaded, err := gitUpload(cfg, dstFiles)
-	var upFiles string
-	for k := range uploaded {
-		upFiles += k + "\n"
-	}
-	if err != nil {
-		prog.Hide()
-		dialog.NewError(err, win)
-	} else {
-		if len(uploaded) > 0 {
-			err = robot(cfg)
-			prog.Hide()
-			if err != nil {
-				dialog.NewError(err, win)
-			} else {
-				dialog.NewInformation("Info", "文件已更新上传:\n"+upFiles, win)
-			}
-		} else {
-			prog.Hide()
-			dialog.NewInformation("Info", "没有可更新的文件!\n", win)
+	msg := fmt.Sprintf("撤销 %s 的导出?\n将恢复 %d 项修改", j.Time.Format("2006-01-02 15:04:05"), len(j.Entries))
+	dialog.ShowConfirm("撤销", msg, func(ok bool) {
+		if !ok {
+			return
 		}
-	}
-
+		_, err := core.UndoLast(cfg)
+		if err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+		dialog.ShowInformation("Info", "已撤销最近一次导出", win)
+	}, win)
 }
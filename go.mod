@@ -5,6 +5,8 @@ go 1.14
 require (
 	fyne.io/fyne v1.3.3
 	github.com/disintegration/imaging v1.6.2
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/go-git/go-git/v5 v5.1.0
 	github.com/mozillazg/go-pinyin v0.18.0
+	golang.org/x/text v0.3.2
 )
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne"
+	"fyne.io/fyne/dialog"
+	"fyne.io/fyne/widget"
+	"github.com/dragon8897/chopper/core"
+)
+
+func createHistoryItemUI(rec *core.HistoryRecord) fyne.CanvasObject {
+	var lines []string
+	if rec.Commit != "" {
+		lines = append(lines, "提交: "+rec.Commit)
+	}
+	for _, r := range rec.Renamed {
+		lines = append(lines, fmt.Sprintf("改名: %s -> %s", r.From, r.To))
+	}
+	for _, file := range rec.Scaled {
+		lines = append(lines, "九宫格: "+file)
+	}
+	for _, file := range rec.Uploaded {
+		lines = append(lines, "上传: "+file)
+	}
+	for _, err := range rec.Errors {
+		lines = append(lines, "错误: "+err.Error())
+	}
+	if rec.Error != "" {
+		lines = append(lines, "中断: "+rec.Error)
+	}
+	if rec.Report != "" {
+		lines = append(lines, "报告: "+rec.Report)
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "没有处理任何文件")
+	}
+	return widget.NewLabel(strings.Join(lines, "\n"))
+}
+
+// showHistory 列出配置的导出历史, 可以按文件名查找
+func showHistory(cfg core.ChopperCfg, win fyne.Window) {
+	records, err := core.LoadHistory(cfg)
+	if err != nil {
+		dialog.ShowError(err, win)
+		return
+	}
+	list := widget.NewAccordionContainer()
+	refresh := func(name string) {
+		list.Items = nil
+		for i := range records {
+			rec := &records[i]
+			if name != "" && !rec.Touches(name) {
+				continue
+			}
+			list.Append(widget.NewAccordionItem(rec.Summary(), createHistoryItemUI(rec)))
+		}
+		list.Refresh()
+	}
+	refresh("")
+
+	search := widget.NewEntry()
+	search.PlaceHolder = "输入文件名查找, 原文件名和新文件名都可以"
+	search.OnChanged = refresh
+
+	box := widget.NewVBox(search, list)
+	if len(records) == 0 {
+		box.Append(widget.NewLabel("还没有导出记录"))
+	}
+	scroll := widget.NewScrollContainer(box)
+	scroll.SetMinSize(fyne.NewSize(600, 400))
+	dialog.ShowCustom("导出历史", "OK", scroll, win)
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"os"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
 	"fyne.io/fyne"
@@ -12,30 +14,15 @@ import (
 	"fyne.io/fyne/layout"
 	"fyne.io/fyne/theme"
 	"fyne.io/fyne/widget"
+	"github.com/dragon8897/chopper/core"
 	"github.com/dragon8897/chopper/extension"
 )
 
-const preferenceChopperCfg = "chopperCfg"
-
-type ChopperCfg struct {
-	ID      int64  `json:"id"`
-	DirPath string `json:"dir"`
-	Git     struct {
-		URL      string `json:"url"`
-		UserName string `json:"name"`
-		Password string `json:"pwd"`
-	} `json:"git"`
-	Robot struct {
-		Name    string `json:"name"`
-		Content string `json:"content"`
-	} `json:"robot"`
-}
-
-var allCfg []ChopperCfg
+var allCfg []core.ChopperCfg
 var chopperPanel *widget.Box
 
 func newChopperCfg(win fyne.Window) {
-	cfg := ChopperCfg{
+	cfg := core.ChopperCfg{
 		ID: time.Now().UnixNano(),
 	}
 	allCfg = append(allCfg, cfg)
@@ -58,13 +45,336 @@ func deleteChopperCfg(id int64) {
 	if index >= count {
 		return
 	}
+	stopWatch(id)
 	allCfg = append(allCfg[:index], allCfg[index+1:]...)
 	children := chopperPanel.Children
 	chopperPanel.Children = append(children[:index], children[index+1:]...)
 	chopperPanel.Refresh()
 }
 
-func createCfgUI(cfg *ChopperCfg, win fyne.Window) fyne.CanvasObject {
+func createStagesUI(cfg *core.ChopperCfg) fyne.CanvasObject {
+	box := widget.NewVBox()
+	var refresh func()
+	refresh = func() {
+		enabled := cfg.Stages
+		if enabled == nil {
+			enabled = core.DefaultStages
+		}
+		// 启用的阶段按执行顺序在前, 未启用的在后
+		names := append([]string(nil), enabled...)
+		for _, name := range core.StageNames() {
+			found := false
+			for _, n := range enabled {
+				if n == name {
+					found = true
+					break
+				}
+			}
+			if !found {
+				names = append(names, name)
+			}
+		}
+
+		box.Children = nil
+		for index, name := range names {
+			i, stage, on := index, name, index < len(enabled)
+			check := widget.NewCheck(stage, func(checked bool) {
+				var stages []string
+				for _, n := range enabled {
+					if n != stage {
+						stages = append(stages, n)
+					}
+				}
+				if checked {
+					stages = append(stages, stage)
+				}
+				cfg.Stages = append([]string{}, stages...)
+				refresh()
+			})
+			check.Checked = on
+			btnUp := widget.NewButtonWithIcon("", theme.MoveUpIcon(), func() {
+				stages := append([]string{}, enabled...)
+				stages[i-1], stages[i] = stages[i], stages[i-1]
+				cfg.Stages = stages
+				refresh()
+			})
+			if !on || i == 0 {
+				btnUp.Disable()
+			}
+			btnDown := widget.NewButtonWithIcon("", theme.MoveDownIcon(), func() {
+				stages := append([]string{}, enabled...)
+				stages[i+1], stages[i] = stages[i], stages[i+1]
+				cfg.Stages = stages
+				refresh()
+			})
+			if !on || i == len(enabled)-1 {
+				btnDown.Disable()
+			}
+			box.Append(widget.NewHBox(check, layout.NewSpacer(), btnUp, btnDown))
+		}
+		box.Refresh()
+	}
+	refresh()
+	return box
+}
+
+func createNamingUI(cfg *core.ChopperCfg) fyne.CanvasObject {
+	pinyinStyle := widget.NewSelect(core.PinyinStyles, func(style string) {
+		cfg.PinyinStyle = style
+	})
+	pinyinStyle.Selected = cfg.PinyinStyle
+	if pinyinStyle.Selected == "" {
+		pinyinStyle.Selected = "tone3"
+	}
+
+	backend := widget.NewSelect(core.Backends, func(backend string) {
+		cfg.Backend = backend
+	})
+	backend.Selected = cfg.Backend
+	if backend.Selected == "" {
+		backend.Selected = core.BackendPinyin
+	}
+
+	entryGlossary := widget.NewEntry()
+	entryGlossary.PlaceHolder = "默认为资源目录中的 .chopper/glossary.json"
+	entryGlossary.Text = cfg.Glossary
+	entryGlossary.OnChanged = func(text string) {
+		cfg.Glossary = text
+	}
+
+	entrySeparator := widget.NewEntry()
+	entrySeparator.PlaceHolder = "默认不分隔"
+	entrySeparator.Text = cfg.Separator
+	entrySeparator.OnChanged = func(text string) {
+		cfg.Separator = text
+	}
+
+	caseStyle := widget.NewSelect(core.CaseStyles, func(style string) {
+		cfg.CaseStyle = style
+	})
+	caseStyle.Selected = cfg.CaseStyle
+	if caseStyle.Selected == "" {
+		caseStyle.Selected = core.CaseKeep
+	}
+
+	dirs := widget.NewCheck("文件夹名也按同样的规则转换", func(checked bool) {
+		cfg.Dirs = checked
+	})
+	dirs.Checked = cfg.Dirs
+
+	entryTemplate := widget.NewEntry()
+	entryTemplate.PlaceHolder = "默认 {type}_{name}"
+	entryTemplate.Text = cfg.Template
+	entryTemplate.OnChanged = func(text string) {
+		cfg.Template = text
+	}
+	entryRetina := widget.NewEntry()
+	entryRetina.PlaceHolder = "默认 " + core.DefaultRetinaSuffix
+	entryRetina.Text = cfg.Retina.Suffix
+	entryRetina.OnChanged = func(text string) {
+		cfg.Retina.Suffix = text
+	}
+
+	templateHint := widget.NewLabel("可用 {path} {dir} {dir1}~{dir9} {type} {name} {scale} {index} {hash}\n[ ] 中的变量为空时整段省略, 例如 {type}_{name}[@{scale}x]")
+
+	return fyne.NewContainerWithLayout(layout.NewFormLayout(), []fyne.CanvasObject{
+		widget.NewLabel("文件夹:"),
+		dirs,
+		widget.NewLabel("转换方式:"),
+		backend,
+		widget.NewLabel("词汇表:"),
+		entryGlossary,
+		widget.NewLabel("拼音风格:"),
+		pinyinStyle,
+		widget.NewLabel("音节分隔符:"),
+		entrySeparator,
+		widget.NewLabel("命名风格:"),
+		caseStyle,
+		widget.NewLabel("倍图后缀:"),
+		entryRetina,
+		widget.NewLabel("命名模板:"),
+		entryTemplate,
+		layout.NewSpacer(),
+		templateHint,
+	}...)
+}
+
+func createPrefixesUI(cfg *core.ChopperCfg) fyne.CanvasObject {
+	box := widget.NewVBox()
+	var refresh func()
+	refresh = func() {
+		prefixes := core.CfgPrefixes(*cfg)
+		box.Children = nil
+		for index, p := range prefixes {
+			i := index
+			// 修改时复制一份, 不修改 DefaultPrefixes
+			edit := func(change func(p *core.PrefixTag)) {
+				cfg.Prefixes = append([]core.PrefixTag{}, core.CfgPrefixes(*cfg)...)
+				change(&cfg.Prefixes[i])
+			}
+			entryPrefix := widget.NewEntry()
+			entryPrefix.PlaceHolder = "前缀, 例如 按钮"
+			entryPrefix.Text = p.Prefix
+			entryPrefix.OnChanged = func(text string) {
+				edit(func(p *core.PrefixTag) { p.Prefix = text })
+			}
+			entryTag := widget.NewEntry()
+			entryTag.PlaceHolder = "标签, 例如 btn"
+			entryTag.Text = p.Tag
+			entryTag.OnChanged = func(text string) {
+				edit(func(p *core.PrefixTag) { p.Tag = text })
+			}
+			btnDelete := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				prefixes := core.CfgPrefixes(*cfg)
+				cfg.Prefixes = append(append([]core.PrefixTag{}, prefixes[:i]...), prefixes[i+1:]...)
+				refresh()
+			})
+			box.Append(fyne.NewContainerWithLayout(layout.NewBorderLayout(nil, nil, nil, btnDelete),
+				btnDelete,
+				fyne.NewContainerWithLayout(layout.NewGridLayout(2), entryPrefix, entryTag),
+			))
+		}
+		box.Append(widget.NewHBox(
+			layout.NewSpacer(),
+			widget.NewButton("恢复默认", func() {
+				cfg.Prefixes = nil
+				refresh()
+			}),
+			widget.NewButtonWithIcon("添加", theme.ContentAddIcon(), func() {
+				cfg.Prefixes = append(append([]core.PrefixTag{}, core.CfgPrefixes(*cfg)...), core.PrefixTag{})
+				refresh()
+			}),
+		))
+		box.Refresh()
+	}
+	refresh()
+	return box
+}
+
+func createOverridesUI(cfg *core.ChopperCfg) fyne.CanvasObject {
+	box := widget.NewVBox()
+	var refresh func()
+	refresh = func() {
+		box.Children = nil
+		box.Append(widget.NewLabel("资源目录中的 .chopper/dict.json 也会被读取, 同一个词以这里的为准"))
+		for index, o := range cfg.Overrides {
+			i := index
+			entryPhrase := widget.NewEntry()
+			entryPhrase.PlaceHolder = "词, 例如 重置"
+			entryPhrase.Text = o.Phrase
+			entryPhrase.OnChanged = func(text string) {
+				cfg.Overrides[i].Phrase = text
+			}
+			entryPinyin := widget.NewEntry()
+			entryPinyin.PlaceHolder = "拼音, 例如 chong2 zhi4"
+			entryPinyin.Text = o.Pinyin
+			entryPinyin.OnChanged = func(text string) {
+				cfg.Overrides[i].Pinyin = text
+			}
+			entryEnglish := widget.NewEntry()
+			entryEnglish.PlaceHolder = "或英文, 例如 reset"
+			entryEnglish.Text = o.English
+			entryEnglish.OnChanged = func(text string) {
+				cfg.Overrides[i].English = text
+			}
+			btnDelete := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				cfg.Overrides = append(cfg.Overrides[:i:i], cfg.Overrides[i+1:]...)
+				refresh()
+			})
+			box.Append(fyne.NewContainerWithLayout(layout.NewBorderLayout(nil, nil, nil, btnDelete),
+				btnDelete,
+				fyne.NewContainerWithLayout(layout.NewGridLayout(3), entryPhrase, entryPinyin, entryEnglish),
+			))
+		}
+		box.Append(widget.NewHBox(
+			layout.NewSpacer(),
+			widget.NewButtonWithIcon("添加", theme.ContentAddIcon(), func() {
+				cfg.Overrides = append(cfg.Overrides, core.Override{})
+				refresh()
+			}),
+		))
+		box.Refresh()
+	}
+	refresh()
+	return box
+}
+
+func createValidationUI(cfg *core.ChopperCfg) fyne.CanvasObject {
+	v := &cfg.Validation
+	entryPattern := widget.NewEntry()
+	entryPattern.PlaceHolder = "例如 ^[a-z_][a-z0-9_]*$"
+	entryPattern.Text = v.Pattern
+	entryPattern.OnChanged = func(text string) {
+		v.Pattern = text
+	}
+
+	entryMaxLength := widget.NewEntry()
+	entryMaxLength.PlaceHolder = "不限制"
+	if v.MaxLength > 0 {
+		entryMaxLength.Text = strconv.Itoa(v.MaxLength)
+	}
+	entryMaxLength.OnChanged = func(text string) {
+		v.MaxLength, _ = strconv.Atoi(text)
+	}
+
+	lowercase := widget.NewCheck("全部转为小写", func(checked bool) {
+		v.Lowercase = checked
+	})
+	lowercase.Checked = v.Lowercase
+
+	entryReserved := widget.NewEntry()
+	entryReserved.PlaceHolder = "逗号分隔, 例如 con,nul,default"
+	entryReserved.Text = strings.Join(v.Reserved, ",")
+	entryReserved.OnChanged = func(text string) {
+		v.Reserved = nil
+		for _, word := range strings.Split(text, ",") {
+			if word = strings.TrimSpace(word); word != "" {
+				v.Reserved = append(v.Reserved, word)
+			}
+		}
+	}
+
+	entryRetina := widget.NewEntry()
+	entryRetina.PlaceHolder = "逗号分隔, 例如 2,3"
+	var scales []string
+	for _, scale := range cfg.Retina.Required {
+		scales = append(scales, strconv.Itoa(scale))
+	}
+	entryRetina.Text = strings.Join(scales, ",")
+	entryRetina.OnChanged = func(text string) {
+		cfg.Retina.Required = nil
+		for _, word := range strings.Split(text, ",") {
+			if scale, err := strconv.Atoi(strings.TrimSpace(word)); err == nil {
+				cfg.Retina.Required = append(cfg.Retina.Required, scale)
+			}
+		}
+	}
+
+	action := widget.NewSelect(core.ValidateActions, func(action string) {
+		v.Action = action
+	})
+	action.Selected = v.Action
+	if action.Selected == "" {
+		action.Selected = core.ValidateBlock
+	}
+
+	return fyne.NewContainerWithLayout(layout.NewFormLayout(), []fyne.CanvasObject{
+		widget.NewLabel("允许的文件名:"),
+		entryPattern,
+		widget.NewLabel("最大长度:"),
+		entryMaxLength,
+		widget.NewLabel("大小写:"),
+		lowercase,
+		widget.NewLabel("保留字:"),
+		entryReserved,
+		widget.NewLabel("需要的倍图:"),
+		entryRetina,
+		widget.NewLabel("不符合时:"),
+		action,
+	}...)
+}
+
+func createCfgUI(cfg *core.ChopperCfg, win fyne.Window) fyne.CanvasObject {
 	content := widget.NewEntry()
 	content.PlaceHolder = "请输入任务完成后机器人的发送内容"
 	content.Text = cfg.Robot.Content
@@ -78,6 +388,8 @@ func createCfgUI(cfg *ChopperCfg, win fyne.Window) fyne.CanvasObject {
 	robots.PlaceHolder = "请先选择一个群聊机器人"
 	robots.Selected = cfg.Robot.Name
 
+	watchRow, restartWatch := createWatchUI(cfg, win)
+
 	btnDir := &widget.Button{}
 	btnDir.Alignment = widget.ButtonAlignLeading
 	if cfg.DirPath == "" {
@@ -94,6 +406,7 @@ func createCfgUI(cfg *ChopperCfg, win fyne.Window) fyne.CanvasObject {
 			cfg.DirPath = dirPath
 			btnDir.Text = dirPath
 			btnDir.Refresh()
+			restartWatch()
 		}, win)
 
 	}
@@ -102,6 +415,31 @@ func createCfgUI(cfg *ChopperCfg, win fyne.Window) fyne.CanvasObject {
 		btnDir,
 	}...)
 
+	collision := widget.NewSelect(core.CollisionPolicies, func(policy string) {
+		cfg.Collision = policy
+	})
+	collision.Selected = cfg.Collision
+	if collision.Selected == "" {
+		collision.Selected = core.CollisionFail
+	}
+	collisionRow := fyne.NewContainerWithLayout(layout.NewFormLayout(), []fyne.CanvasObject{
+		widget.NewLabel("改名冲突:"),
+		collision,
+	}...)
+
+	entryWorkers := widget.NewEntry()
+	entryWorkers.PlaceHolder = "默认使用 CPU 核数"
+	if cfg.Workers > 0 {
+		entryWorkers.Text = strconv.Itoa(cfg.Workers)
+	}
+	entryWorkers.OnChanged = func(text string) {
+		cfg.Workers, _ = strconv.Atoi(text)
+	}
+	entryWorkersRow := fyne.NewContainerWithLayout(layout.NewFormLayout(), []fyne.CanvasObject{
+		widget.NewLabel("并发数:"),
+		entryWorkers,
+	}...)
+
 	entryURL := widget.NewEntry()
 	entryURL.PlaceHolder = "请输入 git 地址"
 	entryURL.Text = cfg.Git.URL
@@ -141,6 +479,18 @@ func createCfgUI(cfg *ChopperCfg, win fyne.Window) fyne.CanvasObject {
 
 	btnStart.Style = widget.PrimaryButton
 
+	btnUndo := widget.NewButton("撤销上次导出", func() {
+		undo(*cfg, win)
+	})
+
+	btnHistory := widget.NewButton("历史", func() {
+		showHistory(*cfg, win)
+	})
+
+	btnMapping := widget.NewButton("查找", func() {
+		showMapping(*cfg, win)
+	})
+
 	return widget.NewVBox(
 		layout.NewSpacer(),
 		widget.NewGroup(" ", layout.NewSpacer()),
@@ -151,6 +501,8 @@ func createCfgUI(cfg *ChopperCfg, win fyne.Window) fyne.CanvasObject {
 			}),
 		),
 		btnDirRow,
+		collisionRow,
+		watchRow,
 		widget.NewAccordionContainer(
 			widget.NewAccordionItem("Git 配置",
 				widget.NewVBox(
@@ -159,6 +511,24 @@ func createCfgUI(cfg *ChopperCfg, win fyne.Window) fyne.CanvasObject {
 					entryGitPwdRow,
 				),
 			),
+			widget.NewAccordionItem("文件命名",
+				widget.NewVBox(
+					createNamingUI(cfg),
+					widget.NewLabel("前缀:"),
+					createPrefixesUI(cfg),
+					widget.NewLabel("词典:"),
+					createOverridesUI(cfg),
+				),
+			),
+			widget.NewAccordionItem("文件名检查",
+				createValidationUI(cfg),
+			),
+			widget.NewAccordionItem("导出阶段",
+				widget.NewVBox(
+					createStagesUI(cfg),
+					entryWorkersRow,
+				),
+			),
 			widget.NewAccordionItem("机器人配置",
 				widget.NewVBox(
 					robots,
@@ -168,6 +538,9 @@ func createCfgUI(cfg *ChopperCfg, win fyne.Window) fyne.CanvasObject {
 		),
 		widget.NewHBox(
 			layout.NewSpacer(),
+			btnMapping,
+			btnHistory,
+			btnUndo,
 			btnStart,
 		),
 		layout.NewSpacer(),
@@ -209,10 +582,10 @@ func main() {
 		os.Setenv("FYNE_FONT", "C:\\Windows\\Fonts\\STXINWEI.TTF")
 		defer os.Unsetenv("FYNE_FONT")
 	}
-	a := app.NewWithID("io.fyne.demo")
+	a := app.NewWithID(core.AppID)
 	a.SetIcon(theme.FyneLogo())
 
-	cfg := a.Preferences().String(preferenceChopperCfg)
+	cfg := a.Preferences().String(core.PreferenceChopperCfg)
 	if len(cfg) > 0 {
 		err := json.Unmarshal([]byte(cfg), &allCfg)
 		if err != nil {
@@ -222,7 +595,7 @@ func main() {
 	defer func() {
 		cfgStr, err := json.Marshal(&allCfg)
 		if err == nil {
-			a.Preferences().SetString(preferenceChopperCfg, string(cfgStr))
+			a.Preferences().SetString(core.PreferenceChopperCfg, string(cfgStr))
 		}
 	}()
 
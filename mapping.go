@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne"
+	"fyne.io/fyne/dialog"
+	"fyne.io/fyne/widget"
+	"github.com/dragon8897/chopper/core"
+)
+
+// showMapping 按原文件名 (中文) 或导出后的文件名查找文件名对照表
+func showMapping(cfg core.ChopperCfg, win fyne.Window) {
+	entries, err := core.LoadMapping(cfg)
+	if err != nil {
+		dialog.ShowError(err, win)
+		return
+	}
+	list := widget.NewVBox()
+	refresh := func(name string) {
+		list.Children = nil
+		for _, e := range core.SearchMapping(entries, name) {
+			list.Append(widget.NewLabel(fmt.Sprintf("%s -> %s\n    首次导出 %s, 最近导出 %s", e.Origin, e.Path,
+				e.FirstExport.Local().Format("2006-01-02 15:04:05"), e.LastExport.Local().Format("2006-01-02 15:04:05"))))
+		}
+		list.Refresh()
+	}
+	refresh("")
+
+	search := widget.NewEntry()
+	search.PlaceHolder = "输入文件名查找, 例如 返回按钮"
+	search.OnChanged = refresh
+
+	box := widget.NewVBox(search, list)
+	if len(entries) == 0 {
+		box.Append(widget.NewLabel("还没有上传过文件, 上传到 git 后会记录文件名对照"))
+	}
+	scroll := widget.NewScrollContainer(box)
+	scroll.SetMinSize(fyne.NewSize(600, 400))
+	dialog.ShowCustom("查找文件", "OK", scroll, win)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne"
+	"fyne.io/fyne/dialog"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/widget"
+	"github.com/dragon8897/chopper/core"
+)
+
+// 正在监视的配置 id -> 停止监视
+var watchers = map[int64]context.CancelFunc{}
+
+func stopWatch(id int64) {
+	if cancel, ok := watchers[id]; ok {
+		cancel()
+		delete(watchers, id)
+	}
+}
+
+// startWatch 使用当前配置开始监视, 已经在监视时先停止
+func startWatch(cfg core.ChopperCfg, onEvent func(core.Event), onRun func(core.WatchRun), onStop func(error)) {
+	stopWatch(cfg.ID)
+	ctx, cancel := context.WithCancel(context.Background())
+	watchers[cfg.ID] = cancel
+	watcher := core.NewWatcher(cfg)
+	watcher.OnEvent = onEvent
+	watcher.OnRun = onRun
+	go func() {
+		onStop(watcher.Run(ctx))
+	}()
+}
+
+// createWatchUI 自动导出的开关和上次自动导出的状态
+// 监视使用开启时的配置, 返回的 restart 在修改资源目录后调用, 使新的文件夹生效
+func createWatchUI(cfg *core.ChopperCfg, win fyne.Window) (fyne.CanvasObject, func()) {
+	status := widget.NewLabel("")
+	var last core.WatchRun
+	btnDetail := widget.NewButton("详情", func() {
+		dialog.ShowCustom("自动导出结果", "OK", createSummaryUI(last.Result, last.Err), win)
+	})
+	btnDetail.Disable()
+
+	onRun := func(run core.WatchRun) {
+		last = run
+		text := fmt.Sprintf("上次自动导出 %s: ", run.Time.Format("15:04:05"))
+		if run.Result != nil {
+			text += strings.SplitN(run.Result.Summary(), "\n", 2)[0]
+		}
+		if run.Err != nil {
+			text += " 失败: " + run.Err.Error()
+		}
+		status.SetText(text)
+		btnDetail.Enable()
+	}
+	// 只显示监视本身的消息, 例如 fsnotify 的错误, 导出过程中每个文件的事件不显示
+	onEvent := func(e core.Event) {
+		if e.Stage == core.StageWatch {
+			status.SetText(e.Message)
+		}
+	}
+	onStop := func(err error) {
+		if err != nil {
+			status.SetText("监视失败: " + err.Error())
+		}
+	}
+
+	check := widget.NewCheck("自动导出", func(checked bool) {
+		cfg.Watch = checked
+		if checked {
+			status.SetText("正在监视")
+			startWatch(*cfg, onEvent, onRun, onStop)
+		} else {
+			stopWatch(cfg.ID)
+			status.SetText("")
+		}
+	})
+	check.Checked = cfg.Watch
+	if cfg.Watch {
+		status.SetText("正在监视")
+		startWatch(*cfg, onEvent, onRun, onStop)
+	}
+	restart := func() {
+		if cfg.Watch {
+			status.SetText("正在监视")
+			startWatch(*cfg, onEvent, onRun, onStop)
+		}
+	}
+	return widget.NewHBox(check, status, layout.NewSpacer(), btnDetail), restart
+}